@@ -3,16 +3,25 @@ package main
 import (
 	"fmt"
 	"log"
+	"net"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
 	"time"
 
 	"net_detect/internal/agent"
+	"net_detect/internal/clock"
 	"net_detect/internal/config"
+	"net_detect/internal/hostnet"
+	"net_detect/internal/metrics"
+	"net_detect/internal/models"
 	"net_detect/internal/ping"
+	"net_detect/internal/reflector"
+	"net_detect/internal/stamp"
 	"net_detect/internal/storage"
 	"net_detect/internal/tasks"
+	"net_detect/internal/throughput"
 	"net_detect/utils"
 )
 
@@ -48,10 +57,16 @@ func main() {
 		}
 	}
 
-	resultStorage, err := storage.NewStorage(storageConfig)
+	pushStorage, err := storage.NewStorage(storageConfig)
 	if err != nil {
 		log.Fatalf("Failed to create storage: %v", err)
 	}
+	// 统计写入结果，按配置保存最新结果供/metrics输出
+	var resultGauges *metrics.ResultGauges
+	if conf.MetricsListen != "" && conf.MetricsExportResults {
+		resultGauges = metrics.NewResultGauges(conf.MetricsResultTTL)
+	}
+	resultStorage := metrics.WrapStorage(pushStorage, resultGauges)
 	defer resultStorage.Close()
 
 	// 创建Agent配置
@@ -59,6 +74,13 @@ func main() {
 		KafkaBrokers: conf.KafkaBrokers,
 		KafkaGroup:   fmt.Sprintf("%s-agent", nodeName),
 		KafkaTopic:   fmt.Sprintf("%s-task", nodeName),
+
+		HeartbeatTopic:    conf.HeartbeatTopic,
+		HeartbeatInterval: conf.HeartbeatInterval,
+		StatusTopic:       conf.StatusTopic,
+	}
+	if conf.DesiredState {
+		agentConfig.DesiredTopic = models.DesiredTopic(nodeName)
 	}
 	log.Printf("Topic: %+v", agentConfig.KafkaTopic)
 
@@ -69,11 +91,111 @@ func main() {
 	}
 
 	// 创建ping执行器和任务
-	pinger := ping.NewPinger(ping.DefaultConfig())
-	pingMeshTask := tasks.NewPingMeshTask(pinger, resultStorage)
+	ping.SetPacketRate(conf.PingMaxPPS)
+	pinger := ping.NewICMPEngine(ping.DefaultConfig())
+	defer pinger.Close()
+	pingMeshTask := tasks.NewPingMeshTask(pinger, resultStorage, conf.PingMaxConcurrency, conf.PingSpreadWindow)
+	tcpPinger := ping.NewTCPPinger(ping.DefaultConfig())
+	tcpPingTask := tasks.NewTCPPingTask(tcpPinger, resultStorage)
+	httpProbeTask := tasks.NewHTTPProbeTask(resultStorage)
+	dnsProbeTask := tasks.NewDNSProbeTask(resultStorage)
+	traceTask := tasks.NewTraceTask(ping.NewTracer(ping.DefaultTraceConfig()), resultStorage)
+	udpPinger := ping.NewUDPPinger(ping.DefaultConfig(), conf.UDPReflectorPort)
+	udpPingTask := tasks.NewUDPPingTask(udpPinger, resultStorage)
+	tlsCertTask := tasks.NewTLSCertTask(resultStorage)
+	pmtuTask := tasks.NewPMTUTask(ping.NewMTUProber(ping.DefaultMTUConfig()), resultStorage)
+	throughputTask := tasks.NewThroughputTask(throughput.NewTester(conf.ThroughputPort, conf.ThroughputSecret), resultStorage)
+	stampSender := ping.NewSTAMPSender(ping.DefaultConfig(), conf.STAMPPort)
+	stampTask := tasks.NewSTAMPTask(stampSender, resultStorage)
+	clock.SetThreshold(conf.ClockOffsetThreshold)
+	clock.SetMaxAge(conf.ClockOffsetMaxAge)
+	ntpTask := tasks.NewNTPTask(resultStorage)
+	flowProber := ping.NewFlowProber(ping.DefaultConfig(), conf.UDPReflectorPort)
+	ecmpProbeTask := tasks.NewECMPProbeTask(flowProber, resultStorage)
+	grpcHealthTask := tasks.NewGRPCHealthTask(resultStorage)
+	quicProbeTask := tasks.NewQUICProbeTask(resultStorage)
+	hostNetTask := tasks.NewHostNetTask(hostnet.NewCollector(), resultStorage)
 
 	// 注册任务
 	agent.RegisterTask(pingMeshTask)
+	agent.RegisterTask(tcpPingTask)
+	agent.RegisterTask(httpProbeTask)
+	agent.RegisterTask(dnsProbeTask)
+	agent.RegisterTask(traceTask)
+	agent.RegisterTask(udpPingTask)
+	agent.RegisterTask(tlsCertTask)
+	agent.RegisterTask(pmtuTask)
+	agent.RegisterTask(throughputTask)
+	agent.RegisterTask(stampTask)
+	agent.RegisterTask(ntpTask)
+	agent.RegisterTask(ecmpProbeTask)
+	agent.RegisterTask(grpcHealthTask)
+	agent.RegisterTask(quicProbeTask)
+	agent.RegisterTask(hostNetTask)
+
+	// 注册外部命令插件，不允许覆盖内置任务
+	for _, plugin := range conf.ExecPlugins {
+		if plugin.Name == "" || plugin.Command == "" {
+			log.Printf("Skip exec plugin with empty name or command: %+v", plugin)
+			continue
+		}
+		if agent.HasTask(plugin.Name) {
+			log.Printf("Skip exec plugin %s: task name already registered", plugin.Name)
+			continue
+		}
+		agent.RegisterTask(tasks.NewExecTask(plugin, resultStorage))
+	}
+
+	// 启动UDP反射端，供其它agent的udpPing任务探测
+	if conf.UDPReflectorPort > 0 {
+		udpReflector := reflector.NewUDPReflector(net.JoinHostPort(conf.ServiceBindAddr, strconv.Itoa(conf.UDPReflectorPort)))
+		go func() {
+			if err := udpReflector.Start(); err != nil {
+				log.Printf("UDP reflector error: %v", err)
+			}
+		}()
+		defer udpReflector.Stop()
+	}
+
+	// 启动带宽测试接收端，供其它agent的throughput任务使用
+	if conf.ThroughputPort > 0 {
+		allowedNets, err := throughput.ParseNets(conf.ThroughputAllowedCIDRs)
+		if err != nil {
+			log.Fatalf("Invalid throughput_allowed_cidrs: %v", err)
+		}
+		throughputServer := throughput.NewServer(net.JoinHostPort(conf.ServiceBindAddr, strconv.Itoa(conf.ThroughputPort)),
+			throughput.Auth{Secret: conf.ThroughputSecret, AllowedNets: allowedNets})
+		go func() {
+			if err := throughputServer.Start(); err != nil {
+				log.Printf("Throughput server error: %v", err)
+			}
+		}()
+		defer throughputServer.Stop()
+	}
+
+	// 启动STAMP反射端，供其它agent及支持TWAMP/STAMP的设备测量
+	if conf.STAMPPort > 0 {
+		stampReflector := stamp.NewReflector(net.JoinHostPort(conf.ServiceBindAddr, strconv.Itoa(conf.STAMPPort)))
+		go func() {
+			if err := stampReflector.Start(); err != nil {
+				log.Printf("STAMP reflector error: %v", err)
+			}
+		}()
+		defer stampReflector.Stop()
+	}
+
+	// 启动指标和健康检查监听
+	if conf.MetricsListen != "" {
+		metricsServer := metrics.NewServer(conf.MetricsListen, resultGauges)
+		metricsServer.AddHealthCheck("kafka_consumer", agent.ConsumerHealthy)
+		metricsServer.AddHealthCheck("storage", resultStorage.Healthy)
+		go func() {
+			if err := metricsServer.Start(); err != nil {
+				log.Printf("Metrics server error: %v", err)
+			}
+		}()
+		defer metricsServer.Stop()
+	}
 
 	// 启动Agent
 	go func() {
@@ -26,6 +26,16 @@ func main() {
 	if err != nil {
 		log.Fatalf("Failed to create controller: %v", err)
 	}
+	if err := ctrl.SetDispatchMode(controller.DispatchMode(conf.DispatchMode)); err != nil {
+		log.Fatalf("Failed to set dispatch mode: %v", err)
+	}
+
+	if conf.HeartbeatTopic != "" {
+		ctrl.WatchAgents(conf.HeartbeatTopic, conf.AgentStaleAfter)
+	}
+	if conf.StatusTopic != "" {
+		ctrl.WatchStatus(conf.StatusTopic)
+	}
 
 	// 创建并启动 API 服务
 	server := api.NewServer(ctrl)
@@ -19,6 +19,7 @@ type Config struct {
 	GatewayAPIURL string
 	Nodes         []string
 	Interval      time.Duration
+	DispatchMode  string
 }
 
 func main() {
@@ -27,11 +28,13 @@ func main() {
 	kafkaBrokers := flag.String("brokers", "localhost:9092", "Kafka brokers")
 	gatewayURL := flag.String("gateway-url", "https://cdn.monitor.just95.net/api/w1/node-gateway", "Gateway API URL")
 	interval := flag.Duration("interval", 5*time.Minute, "Task interval")
+	dispatchMode := flag.String("dispatch-mode", "push", "Task dispatch mode: push or desired")
 	flag.Parse()
 
 	config.KafkaBrokers = strings.Split(*kafkaBrokers, ",")
 	config.GatewayAPIURL = *gatewayURL
 	config.Interval = *interval
+	config.DispatchMode = *dispatchMode
 
 	// 2. 创建controller
 	ctrl, err := controller.NewController(config.KafkaBrokers)
@@ -39,6 +42,9 @@ func main() {
 		log.Fatalf("Failed to create controller: %v", err)
 	}
 	defer ctrl.Stop()
+	if err := ctrl.SetDispatchMode(controller.DispatchMode(config.DispatchMode)); err != nil {
+		log.Fatalf("Failed to set dispatch mode: %v", err)
+	}
 
 	// 3. 创建Gateway Ping任务生成器
 	gwGenerator := gatewayping.NewGenerator(gatewayping.Config{
@@ -3,12 +3,16 @@ package agent
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"sync"
 	"time"
 
+	"net_detect/internal/metrics"
 	"net_detect/internal/models"
 	"net_detect/internal/tasks"
+	"net_detect/utils"
 
 	"github.com/IBM/sarama"
 )
@@ -17,6 +21,11 @@ type Config struct {
 	KafkaBrokers []string
 	KafkaGroup   string
 	KafkaTopic   string
+	DesiredTopic string // 期望状态topic，为空时不启用本地调度
+
+	HeartbeatTopic    string // 心跳topic，为空时不上报心跳
+	HeartbeatInterval time.Duration
+	StatusTopic       string // 执行状态topic，为空时不上报
 }
 
 // internal/agent/agent.go
@@ -27,6 +36,20 @@ type Agent struct {
 	ctx      context.Context
 	cancel   context.CancelFunc
 	config   Config // 添加 config 字段
+
+	scheduler *Scheduler
+	reporter  sarama.AsyncProducer // 心跳和执行状态上报
+
+	reportMu     sync.RWMutex
+	reportClosed bool
+
+	runMu     sync.Mutex
+	runs      map[string]*taskRun // 正在执行的任务，key为MetricName
+	runWG     sync.WaitGroup
+	startedAt time.Time
+
+	consumeMu  sync.Mutex
+	consumeErr error // 最近一次Consume的错误，加入消费组后清空
 }
 
 func NewAgent(config Config) (*Agent, error) {
@@ -45,20 +68,56 @@ func NewAgent(config Config) (*Agent, error) {
 
 	ctx, cancel := context.WithCancel(context.Background())
 
-	return &Agent{
-		consumer: consumer,
-		tasks:    make(map[string]tasks.Task),
-		ctx:      ctx,
-		cancel:   cancel,
-		config:   config, // 保存配置
-	}, nil
+	a := &Agent{
+		consumer:  consumer,
+		tasks:     make(map[string]tasks.Task),
+		runs:      make(map[string]*taskRun),
+		ctx:       ctx,
+		cancel:    cancel,
+		config:    config, // 保存配置
+		startedAt: time.Now(),
+
+		consumeErr: errors.New("not joined consumer group yet"),
+	}
+	if config.DesiredTopic != "" {
+		a.scheduler, err = newScheduler(a, config.KafkaBrokers, config.DesiredTopic)
+		if err != nil {
+			cancel()
+			consumer.Close()
+			return nil, fmt.Errorf("create scheduler failed: %v", err)
+		}
+	}
+	if config.HeartbeatTopic != "" || config.StatusTopic != "" {
+		a.reporter, err = newReportProducer(config.KafkaBrokers)
+		if err != nil {
+			cancel()
+			consumer.Close()
+			return nil, fmt.Errorf("create report producer failed: %v", err)
+		}
+	}
+	metrics.BuildInfo.Set(1, Version)
+	metrics.StartTime.Set(float64(a.startedAt.Unix()))
+	return a, nil
 }
 
 func (a *Agent) RegisterTask(task tasks.Task) {
 	a.tasks[task.Name()] = task
 }
 
+// HasTask 是否已注册指定名称的任务
+func (a *Agent) HasTask(name string) bool {
+	_, ok := a.tasks[name]
+	return ok
+}
+
 func (a *Agent) Start() error {
+	if a.scheduler != nil {
+		go a.scheduler.Run(a.ctx)
+	}
+	if a.config.HeartbeatTopic != "" {
+		go a.runHeartbeat()
+	}
+	go a.runLagMonitor()
 	handler := &ConsumerGroupHandler{agent: a}
 	for {
 		select {
@@ -67,6 +126,7 @@ func (a *Agent) Start() error {
 		default:
 			if err := a.consumer.Consume(a.ctx, []string{a.config.KafkaTopic}, handler); err != nil {
 				log.Printf("Error from consumer: %v", err)
+				a.setConsumeErr(err)
 			}
 		}
 	}
@@ -77,13 +137,40 @@ func (a *Agent) Stop() {
 	if err := a.consumer.Close(); err != nil {
 		log.Printf("Error closing consumer: %v", err)
 	}
+	// 等待正在执行的任务中断并存储部分结果
+	a.runWG.Wait()
+	if a.reporter != nil {
+		a.reportMu.Lock()
+		a.reportClosed = true
+		if err := a.reporter.Close(); err != nil {
+			log.Printf("Error closing report producer: %v", err)
+		}
+		a.reportMu.Unlock()
+	}
+}
+
+func (a *Agent) setConsumeErr(err error) {
+	a.consumeMu.Lock()
+	a.consumeErr = err
+	a.consumeMu.Unlock()
+}
+
+// ConsumerHealthy 已加入消费组且最近一次Consume没有出错时返回nil
+func (a *Agent) ConsumerHealthy() error {
+	a.consumeMu.Lock()
+	defer a.consumeMu.Unlock()
+	return a.consumeErr
 }
 
 type ConsumerGroupHandler struct {
 	agent *Agent
 }
 
-func (h *ConsumerGroupHandler) Setup(_ sarama.ConsumerGroupSession) error   { return nil }
+func (h *ConsumerGroupHandler) Setup(_ sarama.ConsumerGroupSession) error {
+	h.agent.setConsumeErr(nil)
+	return nil
+}
+
 func (h *ConsumerGroupHandler) Cleanup(_ sarama.ConsumerGroupSession) error { return nil }
 
 func (h *ConsumerGroupHandler) ConsumeClaim(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
@@ -95,20 +182,77 @@ func (h *ConsumerGroupHandler) ConsumeClaim(session sarama.ConsumerGroupSession,
 		}
 		log.Printf("Received task: %+v, task targets size: %v", task.TaskName, len(task.Params))
 
-		handler, exists := h.agent.tasks[task.TaskName]
-		if !exists {
-			log.Printf("Unknown task type: %s", task.TaskName)
-			continue
-		}
-		if task.MetricName == "" {
-			task.MetricName = task.TaskName
-		}
-		if err := handler.Execute(task.MetricName, task.Params); err != nil {
-			log.Printf("Failed to execute task %s: %v", task.TaskName, err)
-		}
-		log.Printf("Task: %v, finished", task.TaskName)
-
+		// 异步执行，慢任务不阻塞后续消息
+		h.agent.dispatch(task)
 		session.MarkMessage(message, "")
 	}
 	return nil
 }
+
+// execute 执行一次任务，由dispatch调用，ctx在agent停止或被cancel策略替代时取消，
+// 消息带有Timeout时超时后取消，任务会存储已得到的部分结果
+func (a *Agent) execute(ctx context.Context, task models.TaskMessage) {
+	if ctx.Err() != nil {
+		return
+	}
+	if task.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, task.Timeout)
+		defer cancel()
+	}
+	handler := a.tasks[task.TaskName]
+	metrics.TasksRunning.Add(1)
+	start := time.Now()
+	summary, err := handler.Execute(ctx, task.MetricName, task.Params)
+	metrics.TasksRunning.Add(-1)
+	metrics.TaskDuration.Observe(time.Since(start).Seconds(), task.TaskName)
+	metrics.TaskTargetErrors.Add(float64(summary.Errors), task.TaskName)
+
+	result := "success"
+	if err == nil && ctx.Err() != nil {
+		err = fmt.Errorf("interrupted after %v, partial results stored: %v", time.Since(start).Round(time.Millisecond), ctx.Err())
+		result = "interrupted"
+	}
+	if err != nil {
+		log.Printf("Failed to execute task %s: %v", task.TaskName, err)
+		if result == "success" {
+			result = "error"
+		}
+	}
+	metrics.TaskExecutions.Inc(task.TaskName, result)
+	log.Printf("Task: %v, finished", task.TaskName)
+	a.reportStatus(task, start, summary, err)
+}
+
+// reportStatus 上报一次执行的状态
+func (a *Agent) reportStatus(task models.TaskMessage, start time.Time, summary tasks.Summary, err error) {
+	if a.config.StatusTopic == "" {
+		return
+	}
+	end := time.Now()
+	nodeName, _ := utils.GetNodeName()
+	hostName, _ := utils.GetHostName()
+	status := models.TaskStatus{
+		TaskName:    task.TaskName,
+		MetricName:  task.MetricName,
+		NodeName:    nodeName,
+		HostName:    hostName,
+		Start:       start,
+		End:         end,
+		Duration:    float64(end.Sub(start)) / float64(time.Millisecond),
+		Targets:     summary.Targets,
+		Errors:      summary.Errors,
+		TargetError: summary.FirstError,
+	}
+	// 参数校验失败时任务没有返回统计
+	if status.Targets == 0 {
+		status.Targets = len(task.Params)
+	}
+	if err != nil {
+		status.Error = err.Error()
+	}
+	// 以任务和节点为key，控制器重启后可从压缩topic恢复各节点最近一次的状态
+	if err := a.report(a.config.StatusTopic, task.MetricName+"/"+nodeName, status); err != nil {
+		log.Printf("Failed to report status of task %s: %v", task.MetricName, err)
+	}
+}
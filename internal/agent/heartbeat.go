@@ -0,0 +1,102 @@
+package agent
+
+import (
+	"encoding/json"
+	"log"
+	"sort"
+	"time"
+
+	"net_detect/internal/models"
+	"net_detect/utils"
+
+	"github.com/IBM/sarama"
+)
+
+// Version agent版本，构建时通过 -ldflags "-X net_detect/internal/agent.Version=..." 设置
+var Version = "dev"
+
+const defaultHeartbeatInterval = 30 * time.Second
+
+// newReportProducer 创建心跳和执行状态共用的异步producer，Kafka不可用时不阻塞任务执行
+func newReportProducer(brokers []string) (sarama.AsyncProducer, error) {
+	config := sarama.NewConfig()
+	config.Producer.RequiredAcks = sarama.WaitForLocal
+	config.Producer.Return.Errors = true
+	producer, err := sarama.NewAsyncProducer(brokers, config)
+	if err != nil {
+		return nil, err
+	}
+	go func() {
+		for err := range producer.Errors() {
+			log.Printf("Failed to send report to topic %s: %v", err.Msg.Topic, err.Err)
+		}
+	}()
+	return producer, nil
+}
+
+// report 异步发送一条上报消息，上报topic为压缩topic，同一key只保留最新一条
+func (a *Agent) report(topic, key string, v interface{}) error {
+	value, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	// Stop关闭producer后仍在执行的任务不再上报
+	a.reportMu.RLock()
+	defer a.reportMu.RUnlock()
+	if a.reportClosed {
+		return nil
+	}
+	a.reporter.Input() <- &sarama.ProducerMessage{
+		Topic: topic,
+		Key:   sarama.StringEncoder(key),
+		Value: sarama.ByteEncoder(value),
+	}
+	return nil
+}
+
+// runHeartbeat 启动后立即上报一次，之后按间隔上报，直到agent停止
+func (a *Agent) runHeartbeat() {
+	interval := a.config.HeartbeatInterval
+	if interval <= 0 {
+		interval = defaultHeartbeatInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := a.sendHeartbeat(interval); err != nil {
+			log.Printf("Failed to send heartbeat: %v", err)
+		}
+		select {
+		case <-a.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (a *Agent) sendHeartbeat(interval time.Duration) error {
+	nodeName, _ := utils.GetNodeName()
+	hostName, _ := utils.GetHostName()
+	ips, _ := utils.GetLocalIPs()
+
+	names := make([]string, 0, len(a.tasks))
+	for name := range a.tasks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	now := time.Now()
+	return a.report(a.config.HeartbeatTopic, nodeName, models.AgentHeartbeat{
+		NodeName:  nodeName,
+		HostName:  hostName,
+		Version:   Version,
+		IPs:       ips,
+		Tasks:     names,
+		StartedAt: a.startedAt,
+		Uptime:    now.Sub(a.startedAt).Seconds(),
+		Interval:  interval.Seconds(),
+		Timestamp: now,
+	})
+}
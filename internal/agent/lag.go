@@ -0,0 +1,100 @@
+package agent
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"net_detect/internal/metrics"
+
+	"github.com/IBM/sarama"
+)
+
+const lagInterval = 30 * time.Second
+
+// runLagMonitor 按间隔根据消费组已提交的offset和分区高水位计算消费延迟，
+// 没有新消息时延迟同样会更新，直到agent停止
+func (a *Agent) runLagMonitor() {
+	ticker := time.NewTicker(lagInterval)
+	defer ticker.Stop()
+
+	var admin sarama.ClusterAdmin
+	var client sarama.Client
+	defer func() {
+		// 关闭admin时同时关闭其使用的client
+		if admin != nil {
+			admin.Close()
+		}
+	}()
+	for {
+		if admin == nil {
+			// 出错时下一次重试，Kafka暂时不可用不影响任务消费
+			var err error
+			if client, admin, err = newLagAdmin(a.config.KafkaBrokers); err != nil {
+				log.Printf("Failed to create lag monitor client: %v", err)
+			}
+		}
+		if admin != nil {
+			if err := a.updateConsumerLag(client, admin); err != nil {
+				log.Printf("Failed to update consumer lag: %v", err)
+			}
+		}
+		select {
+		case <-a.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func newLagAdmin(brokers []string) (sarama.Client, sarama.ClusterAdmin, error) {
+	client, err := sarama.NewClient(brokers, sarama.NewConfig())
+	if err != nil {
+		return nil, nil, err
+	}
+	admin, err := sarama.NewClusterAdminFromClient(client)
+	if err != nil {
+		client.Close()
+		return nil, nil, err
+	}
+	return client, admin, nil
+}
+
+// updateConsumerLag 未提交过offset的分区按消费组的初始offset(最早)计算
+func (a *Agent) updateConsumerLag(client sarama.Client, admin sarama.ClusterAdmin) error {
+	topic := a.config.KafkaTopic
+	if err := client.RefreshMetadata(topic); err != nil {
+		return err
+	}
+	partitions, err := client.Partitions(topic)
+	if err != nil {
+		return err
+	}
+	committed, err := admin.ListConsumerGroupOffsets(a.config.KafkaGroup, map[string][]int32{topic: partitions})
+	if err != nil {
+		return err
+	}
+
+	for _, p := range partitions {
+		newest, err := client.GetOffset(topic, p, sarama.OffsetNewest)
+		if err != nil {
+			return fmt.Errorf("get high water mark of %s/%d: %v", topic, p, err)
+		}
+		offset := int64(-1)
+		if block := committed.GetBlock(topic, p); block != nil {
+			offset = block.Offset
+		}
+		if offset < 0 {
+			if offset, err = client.GetOffset(topic, p, sarama.OffsetOldest); err != nil {
+				return fmt.Errorf("get oldest offset of %s/%d: %v", topic, p, err)
+			}
+		}
+		lag := newest - offset
+		if lag < 0 {
+			lag = 0
+		}
+		metrics.ConsumerLag.Set(float64(lag), topic, strconv.Itoa(int(p)))
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package agent
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"net_detect/internal/metrics"
+	"net_detect/internal/models"
+)
+
+// taskRun 同一MetricName任务正在进行的执行
+type taskRun struct {
+	cancel  context.CancelFunc
+	pending *models.TaskMessage // queue策略下等待执行的消息
+}
+
+// dispatch 异步执行任务，不同任务并发执行，同一任务按OverlapPolicy处理重叠，过期消息直接丢弃
+func (a *Agent) dispatch(task models.TaskMessage) {
+	metrics.TasksReceived.Inc(task.TaskName)
+	if _, exists := a.tasks[task.TaskName]; !exists {
+		log.Printf("Unknown task type: %s", task.TaskName)
+		metrics.TasksSkipped.Inc(task.TaskName, "unknown")
+		return
+	}
+	if task.MetricName == "" {
+		task.MetricName = task.TaskName
+	}
+	if task.Expired(time.Now()) {
+		log.Printf("Skip expired task %s, deadline %v", task.MetricName, *task.Deadline)
+		metrics.TasksSkipped.Inc(task.TaskName, "expired")
+		return
+	}
+
+	a.runMu.Lock()
+	defer a.runMu.Unlock()
+	if a.ctx.Err() != nil {
+		return
+	}
+
+	run, running := a.runs[task.MetricName]
+	if !running {
+		a.startRun(task)
+		return
+	}
+	switch task.OverlapPolicy {
+	case models.OverlapQueue:
+		if run.pending != nil {
+			log.Printf("Task %s replaced queued run", task.MetricName)
+			metrics.TasksSkipped.Inc(run.pending.TaskName, "overlap")
+		}
+		run.pending = &task
+	case models.OverlapCancel:
+		log.Printf("Task %s cancelled previous run", task.MetricName)
+		run.cancel()
+		a.startRun(task)
+	default:
+		log.Printf("Skip task %s: previous run not finished", task.MetricName)
+		metrics.TasksSkipped.Inc(task.TaskName, "overlap")
+	}
+}
+
+// startRun 启动一次执行，结束后执行排队的消息，调用方需持有runMu
+func (a *Agent) startRun(task models.TaskMessage) {
+	ctx, cancel := context.WithCancel(a.ctx)
+	run := &taskRun{cancel: cancel}
+	a.runs[task.MetricName] = run
+
+	a.runWG.Add(1)
+	go func() {
+		defer a.runWG.Done()
+		a.execute(ctx, task)
+		cancel()
+
+		a.runMu.Lock()
+		defer a.runMu.Unlock()
+		// cancel策略下已被新的执行替代
+		if a.runs[task.MetricName] != run {
+			return
+		}
+		delete(a.runs, task.MetricName)
+		if run.pending != nil && a.ctx.Err() == nil {
+			next := *run.pending
+			if next.Expired(time.Now()) {
+				log.Printf("Skip expired queued task %s", next.MetricName)
+				metrics.TasksSkipped.Inc(next.TaskName, "expired")
+				return
+			}
+			a.startRun(next)
+		}
+	}()
+}
@@ -0,0 +1,271 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"reflect"
+	"sync"
+	"time"
+
+	"net_detect/internal/models"
+
+	"github.com/IBM/sarama"
+)
+
+const schedulerRetryInterval = 10 * time.Second
+
+// Scheduler 根据期望状态topic在本地按间隔调度任务。每次启动都从最早的offset重放topic重建任务列表，
+// 重放完成后才开始执行，之后的变更立即生效；与Kafka断开时已调度的任务继续执行
+type Scheduler struct {
+	agent  *Agent
+	topic  string
+	client sarama.Client
+
+	mu       sync.Mutex
+	entries  map[string]*scheduleEntry
+	replayed bool
+}
+
+// scheduleEntry 单个任务的调度状态
+type scheduleEntry struct {
+	task   models.DesiredTask
+	cancel context.CancelFunc
+}
+
+func newScheduler(agent *Agent, brokers []string, topic string) (*Scheduler, error) {
+	config := sarama.NewConfig()
+	config.Consumer.Return.Errors = true
+	client, err := sarama.NewClient(brokers, config)
+	if err != nil {
+		return nil, err
+	}
+	return &Scheduler{
+		agent:   agent,
+		topic:   topic,
+		client:  client,
+		entries: make(map[string]*scheduleEntry),
+	}, nil
+}
+
+// Run 消费期望状态topic直到ctx结束，topic不存在或连接失败时定期重试
+func (s *Scheduler) Run(ctx context.Context) {
+	defer s.stopAll()
+	for {
+		if err := s.consume(ctx); err != nil {
+			log.Printf("Scheduler consume %s failed: %v", s.topic, err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(schedulerRetryInterval):
+		}
+	}
+}
+
+func (s *Scheduler) consume(ctx context.Context) error {
+	if err := s.client.RefreshMetadata(s.topic); err != nil {
+		return err
+	}
+	partitions, err := s.client.Partitions(s.topic)
+	if err != nil {
+		return err
+	}
+
+	consumer, err := sarama.NewConsumerFromClient(s.client)
+	if err != nil {
+		return err
+	}
+	defer consumer.Close()
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// 记录每个分区当前的末尾offset，全部消费到末尾后视为重放完成
+	pending := make(map[int32]int64)
+	for _, p := range partitions {
+		newest, err := s.client.GetOffset(s.topic, p, sarama.OffsetNewest)
+		if err != nil {
+			return err
+		}
+		oldest, err := s.client.GetOffset(s.topic, p, sarama.OffsetOldest)
+		if err != nil {
+			return err
+		}
+		if newest > oldest {
+			pending[p] = newest - 1
+		}
+	}
+
+	// 每次重新连接都从头重放，重建与topic一致的任务列表，重放期间已在运行的任务不受影响
+	s.mu.Lock()
+	s.replayed = false
+	s.mu.Unlock()
+	seen := make(map[string]bool)
+
+	messages := make(chan *sarama.ConsumerMessage)
+	errs := make(chan error, len(partitions))
+	for _, p := range partitions {
+		pc, err := consumer.ConsumePartition(s.topic, p, sarama.OffsetOldest)
+		if err != nil {
+			return err
+		}
+		defer pc.Close()
+		go func(pc sarama.PartitionConsumer) {
+			for {
+				select {
+				case msg, ok := <-pc.Messages():
+					if !ok {
+						return
+					}
+					select {
+					case messages <- msg:
+					case <-ctx.Done():
+						return
+					}
+				case err, ok := <-pc.Errors():
+					if !ok {
+						return
+					}
+					errs <- err
+					return
+				}
+			}
+		}(pc)
+	}
+
+	if len(pending) == 0 {
+		s.finishReplay(seen)
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-errs:
+			return err
+		case msg := <-messages:
+			s.apply(msg)
+			if !s.isReplayed() {
+				seen[string(msg.Key)] = true
+				if end, ok := pending[msg.Partition]; ok && msg.Offset >= end {
+					delete(pending, msg.Partition)
+				}
+				if len(pending) == 0 {
+					s.finishReplay(seen)
+				}
+			}
+		}
+	}
+}
+
+// apply 处理一条期望状态消息，空消息表示删除
+func (s *Scheduler) apply(msg *sarama.ConsumerMessage) {
+	key := string(msg.Key)
+	if key == "" {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if msg.Value == nil {
+		if entry, ok := s.entries[key]; ok {
+			entry.stop()
+			delete(s.entries, key)
+			log.Printf("Scheduler removed task %s", key)
+		}
+		return
+	}
+
+	var task models.DesiredTask
+	if err := json.Unmarshal(msg.Value, &task); err != nil {
+		log.Printf("Failed to unmarshal desired task %s: %v", key, err)
+		return
+	}
+	if task.Interval <= 0 {
+		log.Printf("Ignore desired task %s with invalid interval %v", key, task.Interval)
+		return
+	}
+
+	// 定义未变化时保持原有调度，避免控制器重复写入导致任务立即重跑
+	if entry, ok := s.entries[key]; ok {
+		if sameTask(entry.task, task) {
+			return
+		}
+		entry.stop()
+	}
+	entry := &scheduleEntry{task: task}
+	s.entries[key] = entry
+	if s.replayed {
+		entry.start(s.agent)
+	}
+	log.Printf("Scheduler updated task %s, interval: %v, targets size: %v", key, task.Interval, len(task.Params))
+}
+
+// finishReplay 删除重放中未出现的任务，并启动所有任务
+func (s *Scheduler) finishReplay(seen map[string]bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key, entry := range s.entries {
+		if !seen[key] {
+			entry.stop()
+			delete(s.entries, key)
+		}
+	}
+	for _, entry := range s.entries {
+		if entry.cancel == nil {
+			entry.start(s.agent)
+		}
+	}
+	s.replayed = true
+	log.Printf("Scheduler loaded %d tasks from %s", len(s.entries), s.topic)
+}
+
+func (s *Scheduler) isReplayed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.replayed
+}
+
+func (s *Scheduler) stopAll() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, entry := range s.entries {
+		entry.stop()
+	}
+	if err := s.client.Close(); err != nil {
+		log.Printf("Error closing scheduler client: %v", err)
+	}
+}
+
+func sameTask(a, b models.DesiredTask) bool {
+	return a.Interval == b.Interval && reflect.DeepEqual(a.TaskMessage, b.TaskMessage)
+}
+
+// start 立即执行一次，之后按间隔执行，上一次未结束时按任务的OverlapPolicy处理
+func (e *scheduleEntry) start(agent *Agent) {
+	ctx, cancel := context.WithCancel(agent.ctx)
+	e.cancel = cancel
+	task := e.task
+	go func() {
+		ticker := time.NewTicker(task.Interval)
+		defer ticker.Stop()
+
+		agent.dispatch(task.TaskMessage)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				agent.dispatch(task.TaskMessage)
+			}
+		}
+	}()
+}
+
+func (e *scheduleEntry) stop() {
+	if e.cancel != nil {
+		e.cancel()
+		e.cancel = nil
+	}
+}
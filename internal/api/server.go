@@ -28,6 +28,8 @@ func (s *Server) Start(addr string) error {
 	r.HandleFunc("/api/retasks", s.createTask).Methods("POST")
 	r.HandleFunc("/api/tasks/{taskId}", s.deleteTask).Methods("DELETE")
 	r.HandleFunc("/api/tasks/{taskId}", s.getTask).Methods("GET")
+	r.HandleFunc("/api/agents", s.listAgents).Methods("GET")
+	r.HandleFunc("/api/agents/{node}", s.getAgent).Methods("GET")
 
 	log.Printf("Starting API server on %s", addr)
 	return http.ListenAndServe(addr, r)
@@ -63,7 +65,7 @@ func (s *Server) getTask(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	taskId := vars["taskId"]
 
-	task, exists := s.ctrl.GetTask(taskId)
+	task, exists := s.ctrl.GetTaskDetail(taskId)
 	if !exists {
 		http.Error(w, "Task not found", http.StatusNotFound)
 		return
@@ -84,3 +86,32 @@ func (s *Server) deleteTask(w http.ResponseWriter, r *http.Request) {
 
 	w.WriteHeader(http.StatusNoContent)
 }
+
+// 获取所有agent，stale=true/false 按是否失联过滤
+func (s *Server) listAgents(w http.ResponseWriter, r *http.Request) {
+	agents := s.ctrl.ListAgents()
+	if stale := r.URL.Query().Get("stale"); stale != "" {
+		want := stale == "true"
+		filtered := make([]models.AgentInfo, 0, len(agents))
+		for _, a := range agents {
+			if a.Stale == want {
+				filtered = append(filtered, a)
+			}
+		}
+		agents = filtered
+	}
+	json.NewEncoder(w).Encode(agents)
+}
+
+// 获取单个agent
+func (s *Server) getAgent(w http.ResponseWriter, r *http.Request) {
+	node := mux.Vars(r)["node"]
+
+	agent, exists := s.ctrl.GetAgent(node)
+	if !exists {
+		http.Error(w, "Agent not found", http.StatusNotFound)
+		return
+	}
+
+	json.NewEncoder(w).Encode(agent)
+}
@@ -0,0 +1,79 @@
+// Package clock 记录本机时钟相对NTP服务器的偏差，供结果标记时钟是否可信
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// DefaultThreshold 默认的时钟偏差阈值
+const DefaultThreshold = 100 * time.Millisecond
+
+// DefaultMaxAge 默认的测量结果有效期，NTP任务停止后过期的偏差不再用于标记结果
+const DefaultMaxAge = 30 * time.Minute
+
+var state = struct {
+	sync.RWMutex
+	offset    time.Duration
+	measured  time.Time
+	threshold time.Duration
+	maxAge    time.Duration
+}{threshold: DefaultThreshold, maxAge: DefaultMaxAge}
+
+// SetThreshold 设置判断时钟失准的偏差阈值
+func SetThreshold(threshold time.Duration) {
+	state.Lock()
+	defer state.Unlock()
+	state.threshold = threshold
+}
+
+// SetMaxAge 设置测量结果的有效期，不大于0时使用DefaultMaxAge
+func SetMaxAge(maxAge time.Duration) {
+	if maxAge <= 0 {
+		maxAge = DefaultMaxAge
+	}
+	state.Lock()
+	defer state.Unlock()
+	state.maxAge = maxAge
+}
+
+// Update 记录最新测得的时钟偏差
+func Update(offset time.Duration) {
+	state.Lock()
+	defer state.Unlock()
+	state.offset = offset
+	state.measured = time.Now()
+}
+
+// Offset 返回最新测得的时钟偏差及测量时间，未测量时时间为零值
+func Offset() (time.Duration, time.Time) {
+	state.RLock()
+	defer state.RUnlock()
+	return state.offset, state.measured
+}
+
+// Synced 测量结果未过期且偏差在阈值内
+func Synced() bool {
+	state.RLock()
+	defer state.RUnlock()
+	return fresh() && abs(state.offset) <= state.threshold
+}
+
+// Unsynced 测量结果未过期且偏差超过阈值，未测量或结果过期时不认为失准
+func Unsynced() bool {
+	state.RLock()
+	defer state.RUnlock()
+	return fresh() && abs(state.offset) > state.threshold
+}
+
+// fresh 已测量且未超过有效期，调用方需持有锁
+func fresh() bool {
+	return !state.measured.IsZero() && time.Since(state.measured) <= state.maxAge
+}
+
+func abs(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
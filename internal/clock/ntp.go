@@ -0,0 +1,88 @@
+package clock
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"net_detect/utils"
+)
+
+const ntpPacketSize = 48
+
+// NTPResponse 一次SNTP查询的结果
+type NTPResponse struct {
+	ServerIP string
+	LocalIP  string
+	Offset   time.Duration // 本机时钟相对服务器的偏差，正值表示本机落后
+	Delay    time.Duration // 往返时延，扣除服务器处理时间
+	Stratum  int
+	Leap     int    // 0正常，1插入闰秒，2删除闰秒，3未同步
+	RefID    string // 参考源标识
+}
+
+// QueryNTP 向NTP服务器发送一次SNTPv4客户端请求(RFC 4330)
+func QueryNTP(ctx context.Context, server string, timeout time.Duration) (NTPResponse, error) {
+	var resp NTPResponse
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		server = net.JoinHostPort(server, "123")
+	}
+
+	dialer := net.Dialer{Timeout: timeout}
+	conn, err := dialer.DialContext(ctx, "udp", server)
+	if err != nil {
+		return resp, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(timeout))
+	stop := context.AfterFunc(ctx, func() { conn.SetDeadline(time.Now()) })
+	defer stop()
+	resp.LocalIP, _, _ = net.SplitHostPort(conn.LocalAddr().String())
+	resp.ServerIP, _, _ = net.SplitHostPort(conn.RemoteAddr().String())
+
+	req := make([]byte, ntpPacketSize)
+	req[0] = 0<<6 | 4<<3 | 3 // LI=0, VN=4, Mode=3(client)
+	t1 := time.Now()
+	originate := utils.ToNTPTime(t1)
+	binary.BigEndian.PutUint64(req[40:], originate)
+	if _, err := conn.Write(req); err != nil {
+		return resp, err
+	}
+
+	buf := make([]byte, 512)
+	n, err := conn.Read(buf)
+	t4 := time.Now()
+	if err != nil {
+		return resp, err
+	}
+	if n < ntpPacketSize {
+		return resp, errors.New("ntp response too short")
+	}
+	if mode := buf[0] & 0x07; mode != 4 {
+		return resp, fmt.Errorf("unexpected ntp mode %d", mode)
+	}
+	if binary.BigEndian.Uint64(buf[24:]) != originate {
+		return resp, errors.New("ntp originate timestamp mismatch")
+	}
+
+	resp.Leap = int(buf[0] >> 6)
+	resp.Stratum = int(buf[1])
+	if resp.Stratum == 0 {
+		return resp, fmt.Errorf("ntp kiss-o'-death: %s", string(buf[12:16]))
+	}
+	if resp.Stratum == 1 {
+		resp.RefID = strings.TrimRight(string(buf[12:16]), "\x00")
+	} else {
+		resp.RefID = net.IP(buf[12:16]).String()
+	}
+
+	t2 := utils.FromNTPTime(binary.BigEndian.Uint64(buf[32:]))
+	t3 := utils.FromNTPTime(binary.BigEndian.Uint64(buf[40:]))
+	resp.Offset = (t2.Sub(t1) + t3.Sub(t4)) / 2
+	resp.Delay = t4.Sub(t1) - t3.Sub(t2)
+	return resp, nil
+}
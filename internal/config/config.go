@@ -28,9 +28,67 @@ type Config struct {
 	PingCount    int           `yaml:"ping_count"`
 	PingInterval time.Duration `yaml:"ping_interval"`
 	PingTimeout  time.Duration `yaml:"ping_timeout"`
+	// 结果中附带每个序号的RTT
+	PingRecordRtts bool `yaml:"ping_record_rtts"`
+	// pingMesh单次任务同时探测的最大目标数
+	PingMaxConcurrency int `yaml:"ping_max_concurrency"`
+	// agent全局ICMP发包速率上限(每秒报文数)，0表示不限速
+	PingMaxPPS int `yaml:"ping_max_pps"`
+	// pingMesh目标的启动时间均匀分布在该窗口内，0表示同时启动
+	PingSpreadWindow time.Duration `yaml:"ping_spread_window"`
+
+	// 反射端等服务的监听地址，为空时监听所有地址
+	ServiceBindAddr string `yaml:"service_bind_addr"`
+	// UDP反射端口，为0时不启动反射端，udpPing和ecmpProbe的目标默认端口同时使用该值，
+	// 为0时目标默认端口为8862
+	UDPReflectorPort int `yaml:"udp_reflector_port"`
+	// 带宽测试接收端端口，为0时不启动接收端，throughput任务的目标默认端口同时使用该值，
+	// 为0时目标默认端口为8863
+	ThroughputPort int `yaml:"throughput_port"`
+	// 带宽测试共享密钥，接收端和发送端需一致
+	ThroughputSecret string `yaml:"throughput_secret"`
+	// 允许发起带宽测试的来源地址(CIDR或IP)，接收端启用时密钥和白名单至少配置一项
+	ThroughputAllowedCIDRs []string `yaml:"throughput_allowed_cidrs"`
+	// STAMP反射端口，为0时不启动反射端，stamp任务的目标默认端口同时使用该值，
+	// 为0时目标默认端口为862
+	STAMPPort int `yaml:"stamp_port"`
+	// 时钟偏差阈值，超过时结果带上clock_unsynced标记
+	ClockOffsetThreshold time.Duration `yaml:"clock_offset_threshold"`
+	// 时钟偏差测量结果的有效期，应为NTP任务间隔的数倍
+	ClockOffsetMaxAge time.Duration `yaml:"clock_offset_max_age"`
 
 	// 存储类型
 	StorageType string `yaml:"storage_type"`
+
+	// 从 <node>-desired 期望状态topic读取任务并在本地调度
+	DesiredState bool `yaml:"desired_state"`
+
+	// 心跳topic，为空时不上报心跳
+	HeartbeatTopic    string        `yaml:"heartbeat_topic"`
+	HeartbeatInterval time.Duration `yaml:"heartbeat_interval"`
+	// 任务执行状态topic，为空时不上报
+	StatusTopic string `yaml:"status_topic"`
+
+	// 指标和健康检查HTTP监听地址，如":9108"，为空时不启动
+	MetricsListen string `yaml:"metrics_listen"`
+	// /metrics同时输出最新探测结果
+	MetricsExportResults bool `yaml:"metrics_export_results"`
+	// 探测结果超过该时间未更新时不再输出
+	MetricsResultTTL time.Duration `yaml:"metrics_result_ttl"`
+
+	// 外部命令探测插件，每个插件注册为一个任务类型
+	ExecPlugins []ExecPluginConfig `yaml:"exec_plugins"`
+}
+
+// ExecPluginConfig 外部命令插件配置，任务参数以JSON通过stdin传入，
+// stdout输出JSON或Influx行协议
+type ExecPluginConfig struct {
+	Name      string        `yaml:"name"` // 任务名，对应TaskMessage.TaskName
+	Command   string        `yaml:"command"`
+	Args      []string      `yaml:"args"`
+	Env       []string      `yaml:"env"`        // 追加的环境变量，KEY=VALUE
+	Timeout   time.Duration `yaml:"timeout"`    // 默认30s
+	MaxOutput int           `yaml:"max_output"` // stdout最大字节数，默认1MB
 }
 
 var globalConfig *Config
@@ -38,19 +96,27 @@ var globalConfig *Config
 // 默认配置
 func defaultConfig() *Config {
 	return &Config{
-		KafkaBrokers:     []string{"localhost:9092"},
-		KafkaGroup:       "ping-agent",
-		KafkaTopic:       "ping-tasks",
-		KafkaResultTopic: "netdetect-results",
-		VMAddress:        []string{"localhost:8428"},
-		VMUsername:       "net_detect",
-		VMPassword:       "",
-		VMTimeout:        10 * time.Second,
-		VMMaxRetries:     3,
-		PingCount:        10,
-		PingInterval:     100 * time.Millisecond,
-		PingTimeout:      1000 * time.Millisecond,
-		StorageType:      "victoriametrics",
+		KafkaBrokers:         []string{"localhost:9092"},
+		KafkaGroup:           "ping-agent",
+		KafkaTopic:           "ping-tasks",
+		KafkaResultTopic:     "netdetect-results",
+		VMAddress:            []string{"localhost:8428"},
+		VMUsername:           "net_detect",
+		VMPassword:           "",
+		VMTimeout:            10 * time.Second,
+		VMMaxRetries:         3,
+		PingCount:            10,
+		PingInterval:         100 * time.Millisecond,
+		PingTimeout:          1000 * time.Millisecond,
+		PingMaxConcurrency:   256,
+		PingSpreadWindow:     time.Second,
+		ClockOffsetThreshold: 100 * time.Millisecond,
+		ClockOffsetMaxAge:    30 * time.Minute,
+		StorageType:          "victoriametrics",
+		HeartbeatTopic:       "netdetect-heartbeat",
+		HeartbeatInterval:    30 * time.Second,
+		StatusTopic:          "netdetect-task-status",
+		MetricsResultTTL:     10 * time.Minute,
 	}
 }
 
@@ -80,6 +146,20 @@ func LoadConfig() (*Config, error) {
 	pingCount := flag.Int("ping-count", 0, "Number of ping packets to send")
 	pingInterval := flag.Duration("ping-interval", 0, "Interval between ping packets")
 	pingTimeout := flag.Duration("ping-timeout", 0, "Ping timeout")
+	pingRecordRtts := flag.Bool("ping-record-rtts", false, "Record per-sequence RTTs in ping results")
+	pingMaxConcurrency := flag.Int("ping-max-concurrency", 0, "Max targets probed concurrently by one pingMesh task")
+	pingMaxPPS := flag.Int("ping-max-pps", 0, "Agent-wide ICMP packets per second limit")
+	pingSpreadWindow := flag.Duration("ping-spread-window", -1, "Window over which pingMesh target starts are spread, 0 to start all at once")
+	serviceBindAddr := flag.String("service-bind-addr", "", "Bind address for the reflector, STAMP and throughput listeners")
+	udpReflectorPort := flag.Int("udp-reflector-port", 0, "UDP reflector listen port")
+	throughputPort := flag.Int("throughput-port", 0, "Throughput test server listen port")
+	stampPort := flag.Int("stamp-port", 0, "STAMP reflector listen port")
+	desiredState := flag.Bool("desired-state", false, "Schedule tasks locally from the desired-state topic")
+	clockOffsetThreshold := flag.Duration("clock-offset-threshold", 0, "Clock offset above which results are flagged clock_unsynced")
+	clockOffsetMaxAge := flag.Duration("clock-offset-max-age", 0, "Age after which a clock offset measurement is ignored")
+	heartbeatInterval := flag.Duration("heartbeat-interval", 0, "Interval between agent heartbeats")
+	metricsListen := flag.String("metrics-listen", "", "Listen address for /metrics and /healthz, e.g. :9108")
+	metricsExportResults := flag.Bool("metrics-export-results", false, "Export latest probe results on /metrics")
 
 	flag.Parse()
 	// 如果指定了配置文件，则读取配置文件
@@ -134,6 +214,48 @@ func LoadConfig() (*Config, error) {
 	if *pingTimeout != 0 {
 		globalConfig.PingTimeout = *pingTimeout
 	}
+	if *pingRecordRtts {
+		globalConfig.PingRecordRtts = true
+	}
+	if *pingMaxConcurrency != 0 {
+		globalConfig.PingMaxConcurrency = *pingMaxConcurrency
+	}
+	if *pingMaxPPS != 0 {
+		globalConfig.PingMaxPPS = *pingMaxPPS
+	}
+	if *pingSpreadWindow >= 0 {
+		globalConfig.PingSpreadWindow = *pingSpreadWindow
+	}
+	if *serviceBindAddr != "" {
+		globalConfig.ServiceBindAddr = *serviceBindAddr
+	}
+	if *udpReflectorPort != 0 {
+		globalConfig.UDPReflectorPort = *udpReflectorPort
+	}
+	if *throughputPort != 0 {
+		globalConfig.ThroughputPort = *throughputPort
+	}
+	if *stampPort != 0 {
+		globalConfig.STAMPPort = *stampPort
+	}
+	if *desiredState {
+		globalConfig.DesiredState = true
+	}
+	if *clockOffsetThreshold != 0 {
+		globalConfig.ClockOffsetThreshold = *clockOffsetThreshold
+	}
+	if *clockOffsetMaxAge != 0 {
+		globalConfig.ClockOffsetMaxAge = *clockOffsetMaxAge
+	}
+	if *heartbeatInterval != 0 {
+		globalConfig.HeartbeatInterval = *heartbeatInterval
+	}
+	if *metricsListen != "" {
+		globalConfig.MetricsListen = *metricsListen
+	}
+	if *metricsExportResults {
+		globalConfig.MetricsExportResults = true
+	}
 
 	return globalConfig, nil
 }
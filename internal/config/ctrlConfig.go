@@ -3,6 +3,7 @@ package config
 import (
 	"flag"
 	"os"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
@@ -12,6 +13,14 @@ type CtrlConfig struct {
 	KafkaBrokers []string `yaml:"kafka_brokers"`
 	KafkaTopics  []string `yaml:"kafka_topic"`
 	ServerPort   string   `yaml:"server_port"`
+	// 任务下发方式：push为定时推送，desired为写入期望状态由agent本地调度
+	DispatchMode string `yaml:"dispatch_mode"`
+	// agent心跳topic，为空时不跟踪agent状态
+	HeartbeatTopic string `yaml:"heartbeat_topic"`
+	// 超过该时间未收到心跳视为失联，0表示按agent上报间隔的3倍判定
+	AgentStaleAfter time.Duration `yaml:"agent_stale_after"`
+	// 任务执行状态topic，为空时不汇总执行状态
+	StatusTopic string `yaml:"status_topic"`
 }
 
 var globalCtrlConfig *CtrlConfig
@@ -21,6 +30,10 @@ func defaultCtrlConfig() *CtrlConfig {
 		KafkaBrokers: []string{"localhost:9092"},
 		KafkaTopics:  []string{"sqcm01"},
 		ServerPort:   "8088",
+		DispatchMode: "push",
+
+		HeartbeatTopic: "netdetect-heartbeat",
+		StatusTopic:    "netdetect-task-status",
 	}
 }
 func GetCtrlConfig() (*CtrlConfig, error) {
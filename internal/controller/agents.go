@@ -0,0 +1,123 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"net_detect/internal/models"
+
+	"github.com/IBM/sarama"
+)
+
+const staleHeartbeats = 3 // 未配置判定时间时，连续丢失的心跳数
+
+// agentRegistry 根据心跳记录各节点agent的最新状态
+type agentRegistry struct {
+	mu         sync.RWMutex
+	agents     map[string]*models.AgentInfo
+	staleAfter time.Duration
+}
+
+// heartbeatRetention 心跳topic的保留时间，下线节点的心跳过期后被清理
+const heartbeatRetention = 7 * 24 * time.Hour
+
+// WatchAgents 从心跳topic跟踪agent状态。topic按节点名为key压缩，启动时从最早的offset重放
+// 以恢复已知agent列表，staleAfter为0时按各agent上报间隔的3倍判定失联
+func (c *Controller) WatchAgents(topic string, staleAfter time.Duration) {
+	c.agents = &agentRegistry{
+		agents:     make(map[string]*models.AgentInfo),
+		staleAfter: staleAfter,
+	}
+	if err := c.createCompactedTopic(topic, heartbeatRetention); err != nil {
+		log.Printf("Failed to create heartbeat topic: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-c.stopCh
+		cancel()
+	}()
+	go watchTopic(ctx, c.brokers, topic, sarama.OffsetOldest, c.agents.apply)
+}
+
+// ListAgents 获取所有已知agent，按节点名排序
+func (c *Controller) ListAgents() []models.AgentInfo {
+	if c.agents == nil {
+		return []models.AgentInfo{}
+	}
+	return c.agents.list()
+}
+
+// GetAgent 获取单个节点的agent
+func (c *Controller) GetAgent(node string) (models.AgentInfo, bool) {
+	if c.agents == nil {
+		return models.AgentInfo{}, false
+	}
+	return c.agents.get(node)
+}
+
+// apply 记录一条心跳，以Kafka消息时间作为最后在线时间，重放时不会把历史心跳当作在线
+func (r *agentRegistry) apply(msg *sarama.ConsumerMessage) {
+	var hb models.AgentHeartbeat
+	if err := json.Unmarshal(msg.Value, &hb); err != nil {
+		log.Printf("Failed to unmarshal heartbeat: %v", err)
+		return
+	}
+	if hb.NodeName == "" {
+		return
+	}
+	seen := msg.Timestamp
+	if seen.IsZero() {
+		seen = time.Now()
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if old, ok := r.agents[hb.NodeName]; ok && old.LastSeen.After(seen) {
+		return
+	}
+	if _, ok := r.agents[hb.NodeName]; !ok {
+		log.Printf("Agent %s registered, host: %s, version: %s", hb.NodeName, hb.HostName, hb.Version)
+	}
+	r.agents[hb.NodeName] = &models.AgentInfo{AgentHeartbeat: hb, LastSeen: seen}
+}
+
+func (r *agentRegistry) list() []models.AgentInfo {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	now := time.Now()
+	agents := make([]models.AgentInfo, 0, len(r.agents))
+	for _, a := range r.agents {
+		agents = append(agents, r.snapshot(a, now))
+	}
+	sort.Slice(agents, func(i, j int) bool {
+		return agents[i].NodeName < agents[j].NodeName
+	})
+	return agents
+}
+
+func (r *agentRegistry) get(node string) (models.AgentInfo, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	a, ok := r.agents[node]
+	if !ok {
+		return models.AgentInfo{}, false
+	}
+	return r.snapshot(a, time.Now()), true
+}
+
+// snapshot 复制agent状态并计算是否失联
+func (r *agentRegistry) snapshot(a *models.AgentInfo, now time.Time) models.AgentInfo {
+	info := *a
+	staleAfter := r.staleAfter
+	if staleAfter <= 0 {
+		staleAfter = staleHeartbeats * time.Duration(info.Interval*float64(time.Second))
+	}
+	info.Stale = now.Sub(info.LastSeen) > staleAfter
+	return info
+}
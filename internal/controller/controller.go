@@ -19,6 +19,14 @@ type Controller struct {
 	runners   map[string]chan struct{}
 	stopCh    chan struct{}
 	taskMutex sync.RWMutex
+
+	brokers       []string
+	mode          DispatchMode
+	admin         sarama.ClusterAdmin
+	desiredTopics map[string]bool // 已确认存在的期望状态topic
+
+	agents *agentRegistry  // 未启用心跳跟踪时为nil
+	status *statusRegistry // 未启用执行状态汇总时为nil
 }
 
 func NewController(brokers []string) (*Controller, error) {
@@ -33,10 +41,13 @@ func NewController(brokers []string) (*Controller, error) {
 	}
 
 	return &Controller{
-		producer: producer,
-		tasks:    make(map[string]models.Task),
-		runners:  make(map[string]chan struct{}),
-		stopCh:   make(chan struct{}),
+		producer:      producer,
+		tasks:         make(map[string]models.Task),
+		runners:       make(map[string]chan struct{}),
+		stopCh:        make(chan struct{}),
+		brokers:       brokers,
+		mode:          DispatchPush,
+		desiredTopics: make(map[string]bool),
 	}, nil
 }
 
@@ -63,30 +74,25 @@ func (c *Controller) GetTask(taskID string) (models.Task, bool) {
 
 // AddTask 添加任务
 func (c *Controller) AddTask(t models.Task) error {
+	if err := validateTask(t); err != nil {
+		return err
+	}
 	c.taskMutex.Lock()
 	defer c.taskMutex.Unlock()
 
-	if _, exists := c.tasks[t.MetricName]; exists {
-		close(c.runners[t.MetricName])
-	}
-
-	taskKey := t.GenerateKey()
-
-	// 如果任务已存在,关闭其runner
-	if _, exists := c.tasks[taskKey]; exists {
-		if runner, ok := c.runners[taskKey]; ok {
-			close(runner)
-			delete(c.runners, taskKey)
-		}
+	// 期望状态模式下只在任务变更时写入一次，由agent本地调度
+	if c.mode == DispatchDesired {
+		return c.upsertDesired(t.MetricName, t)
 	}
-	stopCh := make(chan struct{})
-	c.runners[t.MetricName] = stopCh
-	go c.runTask(t, stopCh)
+	c.startTask(t.MetricName, t)
 	return nil
 }
 
 // ReloadTask 重写任务
 func (c *Controller) AppendTask(t models.Task, customKey string) error {
+	if err := validateTask(t); err != nil {
+		return err
+	}
 	c.taskMutex.Lock()
 	defer c.taskMutex.Unlock()
 
@@ -94,18 +100,34 @@ func (c *Controller) AppendTask(t models.Task, customKey string) error {
 	if taskKey == "" {
 		taskKey = t.GenerateKey()
 	}
+	if c.mode == DispatchDesired {
+		return c.upsertDesired(taskKey, t)
+	}
+	c.startTask(taskKey, t)
+	return nil
+}
 
-	// 如果任务已存在,关闭其runner
-	if _, exists := c.tasks[taskKey]; exists {
-		if runner, ok := c.runners[taskKey]; ok {
-			close(runner)
-			delete(c.runners, taskKey)
-		}
+// startTask 保存任务并启动推送，已存在时先停止原有的runner，调用方需持有taskMutex
+func (c *Controller) startTask(taskKey string, t models.Task) {
+	if runner, ok := c.runners[taskKey]; ok {
+		close(runner)
 	}
-	c.tasks[t.MetricName] = t
+	c.tasks[taskKey] = t
 	stopCh := make(chan struct{})
-	c.runners[t.MetricName] = stopCh
+	c.runners[taskKey] = stopCh
 	go c.runTask(t, stopCh)
+}
+
+// validateTask 校验任务的可选参数
+func validateTask(t models.Task) error {
+	switch t.OverlapPolicy {
+	case "", models.OverlapSkip, models.OverlapQueue, models.OverlapCancel:
+	default:
+		return fmt.Errorf("task %s: unknown overlap policy %q", t.MetricName, t.OverlapPolicy)
+	}
+	if t.Expiry < 0 || t.Timeout < 0 {
+		return fmt.Errorf("task %s: expiry and timeout must not be negative", t.MetricName)
+	}
 	return nil
 }
 
@@ -114,6 +136,15 @@ func (c *Controller) RemoveTask(taskID string) error {
 	c.taskMutex.Lock()
 	defer c.taskMutex.Unlock()
 
+	if c.mode == DispatchDesired {
+		t, exists := c.tasks[taskID]
+		if !exists {
+			return fmt.Errorf("task %s not found", taskID)
+		}
+		delete(c.tasks, taskID)
+		return c.removeDesired(taskID, t)
+	}
+
 	if stopCh, exists := c.runners[taskID]; exists {
 		close(stopCh)
 		delete(c.runners, taskID)
@@ -134,6 +165,11 @@ func (c *Controller) Stop() {
 	if err := c.producer.Close(); err != nil {
 		log.Printf("Failed to close producer: %v", err)
 	}
+	if c.admin != nil {
+		if err := c.admin.Close(); err != nil {
+			log.Printf("Failed to close cluster admin: %v", err)
+		}
+	}
 }
 
 // sendTaskMessages 为每个节点发送任务消息到对应的topic
@@ -142,10 +178,28 @@ func (c *Controller) sendTaskMessages(t models.Task) error {
 	topics := t.GetTopics()
 
 	for i, topic := range topics {
+		if c.agents != nil {
+			if agent, ok := c.agents.get(t.NodeNames[i]); !ok || agent.Stale {
+				log.Printf("No live agent on node %s for task %s", t.NodeNames[i], t.MetricName)
+			}
+		}
+		now := time.Now()
 		msg := models.TaskMessage{
-			TaskName:   t.Name,
-			MetricName: t.MetricName,
-			Params:     t.Params,
+			TaskName:      t.Name,
+			MetricName:    t.MetricName,
+			Params:        t.Params,
+			DispatchedAt:  &now,
+			OverlapPolicy: t.OverlapPolicy,
+			Timeout:       t.Timeout,
+		}
+		// agent积压的消息超过有效期后不再执行，下一次推送会替代它
+		expiry := t.Expiry
+		if expiry <= 0 {
+			expiry = t.Interval
+		}
+		if expiry > 0 {
+			deadline := now.Add(expiry)
+			msg.Deadline = &deadline
 		}
 
 		msgBytes, err := json.Marshal(msg)
@@ -170,14 +224,29 @@ func (c *Controller) sendTaskMessages(t models.Task) error {
 
 // Start 启动控制器
 func (c *Controller) Start() error {
-	c.taskMutex.RLock()
-	defer c.taskMutex.RUnlock()
+	c.taskMutex.Lock()
+	defer c.taskMutex.Unlock()
 
 	// 检查 producer 是否就绪
 	if c.producer == nil {
 		return fmt.Errorf("producer is not initialized")
 	}
 
+	// 期望状态模式下先从各节点的期望状态topic恢复任务，再重新写入一次，
+	// agent收到相同定义时不会重复调度
+	if c.mode == DispatchDesired {
+		if err := c.loadDesired(); err != nil {
+			log.Printf("Failed to load tasks from desired-state topics: %v", err)
+		}
+		for key, task := range c.tasks {
+			if err := c.publishDesired(key, task, nil); err != nil {
+				log.Printf("Failed to publish task %s: %v", key, err)
+			}
+		}
+		log.Printf("Controller started with %d tasks in desired-state mode", len(c.tasks))
+		return nil
+	}
+
 	// 遍历所有已存在的任务并启动
 	for taskID, task := range c.tasks {
 		if _, exists := c.runners[taskID]; !exists {
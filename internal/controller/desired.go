@@ -0,0 +1,287 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"net_detect/internal/models"
+
+	"github.com/IBM/sarama"
+)
+
+// DispatchMode 任务下发方式
+type DispatchMode string
+
+const (
+	// DispatchPush 按Interval定时向 <node>-task 推送任务消息，agent收到后执行一次
+	DispatchPush DispatchMode = "push"
+	// DispatchDesired 任务变更时向 <node>-desired 压缩topic写入任务定义，由agent本地调度
+	DispatchDesired DispatchMode = "desired"
+)
+
+// SetDispatchMode 设置任务下发方式，需在添加任务前调用
+func (c *Controller) SetDispatchMode(mode DispatchMode) error {
+	switch mode {
+	case "", DispatchPush:
+		mode = DispatchPush
+	case DispatchDesired:
+		admin, err := sarama.NewClusterAdmin(c.brokers, adminConfig())
+		if err != nil {
+			return fmt.Errorf("failed to create cluster admin: %v", err)
+		}
+		c.admin = admin
+	default:
+		return fmt.Errorf("unknown dispatch mode %q", mode)
+	}
+
+	c.taskMutex.Lock()
+	c.mode = mode
+	c.taskMutex.Unlock()
+	return nil
+}
+
+func adminConfig() *sarama.Config {
+	config := sarama.NewConfig()
+	// 创建topic时副本数使用broker默认值需要2.4以上版本
+	config.Version = sarama.V2_4_0_0
+	return config
+}
+
+// ensureDesiredTopic 创建压缩topic，已存在时忽略。创建失败时返回错误，
+// 避免写入时由broker自动创建未开启压缩的topic
+func (c *Controller) ensureDesiredTopic(topic string) error {
+	if c.admin == nil {
+		return errors.New("cluster admin is not initialized")
+	}
+	if c.desiredTopics[topic] {
+		return nil
+	}
+	if err := createCompactedTopic(c.admin, topic, 0); err != nil {
+		return err
+	}
+	c.desiredTopics[topic] = true
+	return nil
+}
+
+// createCompactedTopic 创建按key压缩的topic，已存在时忽略；retention大于0时同时删除超过保留时间的消息
+func createCompactedTopic(admin sarama.ClusterAdmin, topic string, retention time.Duration) error {
+	entries := map[string]*string{
+		"cleanup.policy": stringPtr("compact"),
+	}
+	if retention > 0 {
+		entries["cleanup.policy"] = stringPtr("compact,delete")
+		entries["retention.ms"] = stringPtr(strconv.FormatInt(retention.Milliseconds(), 10))
+	}
+	err := admin.CreateTopic(topic, &sarama.TopicDetail{
+		NumPartitions:     1,
+		ReplicationFactor: -1,
+		ConfigEntries:     entries,
+	}, false)
+	var topicErr *sarama.TopicError
+	if err != nil && !(errors.As(err, &topicErr) && topicErr.Err == sarama.ErrTopicAlreadyExists) {
+		return fmt.Errorf("failed to create compacted topic %s: %v", topic, err)
+	}
+	return nil
+}
+
+// createCompactedTopic 使用控制器的cluster admin创建压缩topic，推送模式下临时创建admin
+func (c *Controller) createCompactedTopic(topic string, retention time.Duration) error {
+	admin := c.admin
+	if admin == nil {
+		var err error
+		admin, err = sarama.NewClusterAdmin(c.brokers, adminConfig())
+		if err != nil {
+			return fmt.Errorf("failed to create cluster admin: %v", err)
+		}
+		defer admin.Close()
+	}
+	return createCompactedTopic(admin, topic, retention)
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
+
+// upsertDesired 以key保存并写入任务定义，调用方需持有taskMutex
+func (c *Controller) upsertDesired(key string, t models.Task) error {
+	if t.Interval <= 0 {
+		return fmt.Errorf("task %s: interval is required in desired-state mode", t.MetricName)
+	}
+	var old *models.Task
+	if prev, exists := c.tasks[key]; exists {
+		old = &prev
+	}
+	c.tasks[key] = t
+	return c.publishDesired(key, t, old)
+}
+
+// publishDesired 以key写入任务定义，并为不再执行该任务的节点写入删除标记，调用方需持有taskMutex
+func (c *Controller) publishDesired(key string, t models.Task, old *models.Task) error {
+	msg := models.DesiredTask{
+		TaskMessage: models.TaskMessage{
+			TaskName:      t.Name,
+			MetricName:    t.MetricName,
+			Params:        t.Params,
+			OverlapPolicy: t.OverlapPolicy,
+			Timeout:       t.Timeout,
+		},
+		Interval:  t.Interval,
+		Tags:      t.Tags,
+		UpdatedAt: time.Now(),
+	}
+	msgBytes, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to marshal message: %v", err)
+	}
+
+	var failed []string
+	for _, node := range t.NodeNames {
+		if err := c.sendDesired(node, key, msgBytes); err != nil {
+			log.Printf("Failed to publish task %s to node %s: %v", t.MetricName, node, err)
+			failed = append(failed, node)
+			continue
+		}
+		log.Printf("Published task %s to node %s, interval: %v, len: %v", t.MetricName, node, t.Interval, len(t.Params))
+	}
+
+	if old != nil {
+		current := make(map[string]bool, len(t.NodeNames))
+		for _, node := range t.NodeNames {
+			current[node] = true
+		}
+		for _, node := range old.NodeNames {
+			if current[node] {
+				continue
+			}
+			if err := c.sendDesired(node, key, nil); err != nil {
+				log.Printf("Failed to remove task %s from node %s: %v", t.MetricName, node, err)
+				failed = append(failed, node)
+			}
+		}
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to publish task %s to nodes %v", t.MetricName, failed)
+	}
+	return nil
+}
+
+// removeDesired 为任务的所有节点写入删除标记，调用方需持有taskMutex
+func (c *Controller) removeDesired(key string, t models.Task) error {
+	var failed []string
+	for _, node := range t.NodeNames {
+		if err := c.sendDesired(node, key, nil); err != nil {
+			log.Printf("Failed to remove task %s from node %s: %v", t.MetricName, node, err)
+			failed = append(failed, node)
+			continue
+		}
+		log.Printf("Removed task %s from node %s", t.MetricName, node)
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to remove task %s from nodes %v", t.MetricName, failed)
+	}
+	return nil
+}
+
+// sendDesired 以任务ID为key写入节点的期望状态topic，value为nil时为删除标记
+func (c *Controller) sendDesired(node, key string, value []byte) error {
+	topic := models.DesiredTopic(node)
+	if err := c.ensureDesiredTopic(topic); err != nil {
+		return err
+	}
+
+	msg := &sarama.ProducerMessage{
+		Topic: topic,
+		Key:   sarama.StringEncoder(key),
+	}
+	if value != nil {
+		msg.Value = sarama.ByteEncoder(value)
+	}
+	_, _, err := c.producer.SendMessage(msg)
+	return err
+}
+
+// loadDesired 重放所有期望状态topic，恢复控制器重启前下发的任务，调用方需持有taskMutex。
+// 同一任务在各节点上的定义不一致时(如部分节点写入失败)使用最新的定义，启动时会重新写入
+func (c *Controller) loadDesired() error {
+	if c.admin == nil {
+		return errors.New("cluster admin is not initialized")
+	}
+	topics, err := c.admin.ListTopics()
+	if err != nil {
+		return fmt.Errorf("failed to list topics: %v", err)
+	}
+	names := make([]string, 0, len(topics))
+	for topic := range topics {
+		if strings.HasSuffix(topic, models.DesiredTopicSuffix) {
+			names = append(names, topic)
+		}
+	}
+	sort.Strings(names)
+
+	type loadedTask struct {
+		task      models.Task
+		updatedAt time.Time
+	}
+	loaded := make(map[string]*loadedTask)
+	for _, topic := range names {
+		node := strings.TrimSuffix(topic, models.DesiredTopicSuffix)
+		// 同一key只保留最后一条，空消息表示已删除
+		latest := make(map[string][]byte)
+		err := replayTopic(c.brokers, topic, func(msg *sarama.ConsumerMessage) {
+			if len(msg.Key) > 0 {
+				latest[string(msg.Key)] = msg.Value
+			}
+		})
+		if err != nil {
+			log.Printf("Failed to replay desired-state topic %s: %v", topic, err)
+			continue
+		}
+		c.desiredTopics[topic] = true
+
+		for key, value := range latest {
+			if value == nil {
+				continue
+			}
+			var d models.DesiredTask
+			if err := json.Unmarshal(value, &d); err != nil {
+				log.Printf("Failed to unmarshal desired task %s in %s: %v", key, topic, err)
+				continue
+			}
+			l, ok := loaded[key]
+			if !ok {
+				l = &loadedTask{}
+				loaded[key] = l
+			}
+			if !ok || d.UpdatedAt.After(l.updatedAt) {
+				nodes := l.task.NodeNames
+				l.task = models.Task{
+					Name:          d.TaskName,
+					MetricName:    d.MetricName,
+					NodeNames:     nodes,
+					Params:        d.Params,
+					Interval:      d.Interval,
+					Tags:          d.Tags,
+					OverlapPolicy: d.OverlapPolicy,
+					Timeout:       d.Timeout,
+				}
+				l.updatedAt = d.UpdatedAt
+			}
+			l.task.NodeNames = append(l.task.NodeNames, node)
+		}
+	}
+
+	for key, l := range loaded {
+		if _, exists := c.tasks[key]; !exists {
+			c.tasks[key] = l.task
+		}
+	}
+	log.Printf("Loaded %d tasks from %d desired-state topics", len(loaded), len(names))
+	return nil
+}
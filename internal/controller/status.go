@@ -0,0 +1,97 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	"net_detect/internal/models"
+
+	"github.com/IBM/sarama"
+)
+
+// statusRegistry 汇总各任务在每个节点上最近一次的执行状态
+type statusRegistry struct {
+	mu   sync.RWMutex
+	runs map[string]map[string]models.TaskStatus // metricName -> node -> 状态
+}
+
+// statusRetention 执行状态topic的保留时间，已删除任务的状态过期后被清理
+const statusRetention = 7 * 24 * time.Hour
+
+// WatchStatus 从执行状态topic汇总任务执行情况。topic按任务和节点为key压缩，
+// 启动时从最早的offset重放以恢复各节点最近一次的状态
+func (c *Controller) WatchStatus(topic string) {
+	c.status = &statusRegistry{runs: make(map[string]map[string]models.TaskStatus)}
+	if err := c.createCompactedTopic(topic, statusRetention); err != nil {
+		log.Printf("Failed to create status topic: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-c.stopCh
+		cancel()
+	}()
+	go watchTopic(ctx, c.brokers, topic, sarama.OffsetOldest, c.status.apply)
+}
+
+// GetTaskDetail 获取任务定义及各节点最近一次执行状态，任务定义不在本控制器时仅返回执行状态
+func (c *Controller) GetTaskDetail(taskID string) (models.TaskDetail, bool) {
+	t, exists := c.GetTask(taskID)
+	if !exists {
+		t = models.Task{MetricName: taskID}
+	}
+	detail := models.TaskDetail{Task: t, LastRuns: map[string]models.TaskStatus{}}
+	if c.status != nil {
+		detail.LastRuns = c.status.lastRuns(t.MetricName)
+	}
+	if !exists && len(detail.LastRuns) == 0 {
+		return models.TaskDetail{}, false
+	}
+	// 多数任务在目标全部失败时不返回错误，需同时根据目标失败数判断
+	for _, run := range detail.LastRuns {
+		if run.Error != "" || (run.Targets > 0 && run.Errors >= run.Targets) {
+			detail.Failing = true
+		}
+		if run.Errors > 0 {
+			detail.Degraded = true
+		}
+	}
+	return detail, true
+}
+
+// apply 记录一条执行状态，乱序到达的旧状态忽略
+func (r *statusRegistry) apply(msg *sarama.ConsumerMessage) {
+	var status models.TaskStatus
+	if err := json.Unmarshal(msg.Value, &status); err != nil {
+		log.Printf("Failed to unmarshal task status: %v", err)
+		return
+	}
+	if status.MetricName == "" || status.NodeName == "" {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	nodes, ok := r.runs[status.MetricName]
+	if !ok {
+		nodes = make(map[string]models.TaskStatus)
+		r.runs[status.MetricName] = nodes
+	}
+	if old, ok := nodes[status.NodeName]; ok && old.Start.After(status.Start) {
+		return
+	}
+	nodes[status.NodeName] = status
+}
+
+func (r *statusRegistry) lastRuns(metricName string) map[string]models.TaskStatus {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	runs := make(map[string]models.TaskStatus, len(r.runs[metricName]))
+	for node, status := range r.runs[metricName] {
+		runs[node] = status
+	}
+	return runs
+}
@@ -0,0 +1,142 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/IBM/sarama"
+)
+
+const (
+	watchRetryInterval = 10 * time.Second
+	replayTimeout      = 30 * time.Second
+)
+
+// watchTopic 从offset开始消费topic的所有分区直到ctx结束，topic不存在或连接失败时定期重试
+func watchTopic(ctx context.Context, brokers []string, topic string, offset int64, apply func(*sarama.ConsumerMessage)) {
+	for {
+		if err := consumeTopic(ctx, brokers, topic, offset, apply); err != nil {
+			log.Printf("Consume topic %s failed: %v", topic, err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(watchRetryInterval):
+		}
+	}
+}
+
+func consumeTopic(ctx context.Context, brokers []string, topic string, offset int64, apply func(*sarama.ConsumerMessage)) error {
+	config := sarama.NewConfig()
+	config.Consumer.Return.Errors = true
+	consumer, err := sarama.NewConsumer(brokers, config)
+	if err != nil {
+		return err
+	}
+	defer consumer.Close()
+
+	partitions, err := consumer.Partitions(topic)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	errs := make(chan error, len(partitions))
+	for _, p := range partitions {
+		pc, err := consumer.ConsumePartition(topic, p, offset)
+		if err != nil {
+			return err
+		}
+		defer pc.Close()
+		go func(pc sarama.PartitionConsumer) {
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case msg, ok := <-pc.Messages():
+					if !ok {
+						return
+					}
+					apply(msg)
+				case err, ok := <-pc.Errors():
+					if !ok {
+						return
+					}
+					errs <- err
+					return
+				}
+			}
+		}(pc)
+	}
+
+	select {
+	case <-ctx.Done():
+		return nil
+	case err := <-errs:
+		return err
+	}
+}
+
+// replayTopic 从最早的offset读取topic所有分区直到开始时的末尾，用于启动时恢复状态
+func replayTopic(brokers []string, topic string, apply func(*sarama.ConsumerMessage)) error {
+	client, err := sarama.NewClient(brokers, sarama.NewConfig())
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	consumer, err := sarama.NewConsumerFromClient(client)
+	if err != nil {
+		return err
+	}
+	defer consumer.Close()
+
+	partitions, err := client.Partitions(topic)
+	if err != nil {
+		return err
+	}
+	for _, p := range partitions {
+		newest, err := client.GetOffset(topic, p, sarama.OffsetNewest)
+		if err != nil {
+			return err
+		}
+		oldest, err := client.GetOffset(topic, p, sarama.OffsetOldest)
+		if err != nil {
+			return err
+		}
+		if newest <= oldest {
+			continue
+		}
+		if err := replayPartition(consumer, topic, p, oldest, newest, apply); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func replayPartition(consumer sarama.Consumer, topic string, partition int32, oldest, newest int64, apply func(*sarama.ConsumerMessage)) error {
+	pc, err := consumer.ConsumePartition(topic, partition, oldest)
+	if err != nil {
+		return err
+	}
+	defer pc.Close()
+
+	timeout := time.NewTimer(replayTimeout)
+	defer timeout.Stop()
+	for {
+		select {
+		case msg, ok := <-pc.Messages():
+			if !ok {
+				return fmt.Errorf("partition %d closed during replay", partition)
+			}
+			apply(msg)
+			if msg.Offset >= newest-1 {
+				return nil
+			}
+		case <-timeout.C:
+			return fmt.Errorf("replay partition %d timed out after %v", partition, replayTimeout)
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package hostnet
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Sample 一次采样的原始计数
+type Sample struct {
+	Time           time.Time
+	SNMP           map[string]map[string]int64 // /proc/net/snmp
+	Netstat        map[string]map[string]int64 // /proc/net/netstat
+	Dev            map[string]DevCounters      // /proc/net/dev
+	HasConntrack   bool                        // 未加载nf_conntrack时为false
+	ConntrackCount uint64
+	ConntrackMax   uint64
+}
+
+// ReadSample 读取当前网络命名空间的计数
+func ReadSample() (*Sample, error) {
+	s := &Sample{Time: time.Now()}
+	var err error
+	if s.SNMP, err = readKeyValueTable(procPath("net", "snmp")); err != nil {
+		return nil, fmt.Errorf("read snmp: %v", err)
+	}
+	if s.Netstat, err = readKeyValueTable(procPath("net", "netstat")); err != nil {
+		return nil, fmt.Errorf("read netstat: %v", err)
+	}
+	if s.Dev, err = readNetDev(procPath("net", "dev")); err != nil {
+		return nil, fmt.Errorf("read dev: %v", err)
+	}
+
+	count, err := readUint(procPath("sys", "net", "netfilter", "nf_conntrack_count"))
+	if err == nil {
+		s.ConntrackCount = count
+		s.ConntrackMax, err = readUint(procPath("sys", "net", "netfilter", "nf_conntrack_max"))
+		s.HasConntrack = err == nil
+	}
+	return s, nil
+}
+
+// counter 需要计算速率的协议计数
+type counter struct {
+	field   string
+	netstat bool // 是否来自/proc/net/netstat
+	prefix  string
+	key     string
+}
+
+var counters = []counter{
+	{"ip_in_hdr_errors", false, "Ip", "InHdrErrors"},
+	{"ip_in_discards", false, "Ip", "InDiscards"},
+	{"ip_out_discards", false, "Ip", "OutDiscards"},
+	{"ip_out_no_routes", false, "Ip", "OutNoRoutes"},
+	{"ip_reasm_fails", false, "Ip", "ReasmFails"},
+	{"icmp_in_msgs", false, "Icmp", "InMsgs"},
+	{"icmp_in_errors", false, "Icmp", "InErrors"},
+	{"icmp_in_csum_errors", false, "Icmp", "InCsumErrors"},
+	{"icmp_in_dest_unreachs", false, "Icmp", "InDestUnreachs"},
+	{"icmp_in_time_excds", false, "Icmp", "InTimeExcds"},
+	{"icmp_out_msgs", false, "Icmp", "OutMsgs"},
+	{"icmp_out_errors", false, "Icmp", "OutErrors"},
+	{"icmp_out_dest_unreachs", false, "Icmp", "OutDestUnreachs"},
+	{"tcp_in_segs", false, "Tcp", "InSegs"},
+	{"tcp_out_segs", false, "Tcp", "OutSegs"},
+	{"tcp_retrans_segs", false, "Tcp", "RetransSegs"},
+	{"tcp_in_errs", false, "Tcp", "InErrs"},
+	{"tcp_in_csum_errors", false, "Tcp", "InCsumErrors"},
+	{"tcp_out_rsts", false, "Tcp", "OutRsts"},
+	{"tcp_attempt_fails", false, "Tcp", "AttemptFails"},
+	{"tcp_estab_resets", false, "Tcp", "EstabResets"},
+	{"tcp_timeouts", true, "TcpExt", "TCPTimeouts"},
+	{"tcp_syn_retrans", true, "TcpExt", "TCPSynRetrans"},
+	{"tcp_lost_retransmit", true, "TcpExt", "TCPLostRetransmit"},
+	{"tcp_listen_overflows", true, "TcpExt", "ListenOverflows"},
+	{"tcp_listen_drops", true, "TcpExt", "ListenDrops"},
+	{"tcp_backlog_drop", true, "TcpExt", "TCPBacklogDrop"},
+	{"udp_in_errors", false, "Udp", "InErrors"},
+	{"udp_no_ports", false, "Udp", "NoPorts"},
+	{"udp_rcvbuf_errors", false, "Udp", "RcvbufErrors"},
+	{"udp_sndbuf_errors", false, "Udp", "SndbufErrors"},
+	{"udp_in_csum_errors", false, "Udp", "InCsumErrors"},
+}
+
+// Rates 两次采样间的每秒速率，字段名即输出的field名
+type Rates struct {
+	Time     time.Time
+	Interval time.Duration
+	Host     map[string]float64            // 协议计数速率及比例
+	Devices  map[string]map[string]float64 // 网卡计数速率
+}
+
+// Collector 按key保存上一次采样，计算同一key相邻两次采样间的速率。
+// 不同调度的任务使用不同的key，互不影响各自的采样间隔
+type Collector struct {
+	mu   sync.Mutex
+	prev map[string]*Sample
+}
+
+func NewCollector() *Collector {
+	return &Collector{prev: make(map[string]*Sample)}
+}
+
+// Collect 采样并返回与该key上一次采样之间的速率，首次采样时速率为nil
+func (c *Collector) Collect(key string) (*Sample, *Rates, error) {
+	cur, err := ReadSample()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	c.mu.Lock()
+	prev := c.prev[key]
+	c.prev[key] = cur
+	c.mu.Unlock()
+
+	if prev == nil {
+		return cur, nil, nil
+	}
+	return cur, calcRates(prev, cur), nil
+}
+
+func calcRates(prev, cur *Sample) *Rates {
+	interval := cur.Time.Sub(prev.Time)
+	rates := &Rates{
+		Time:     cur.Time,
+		Interval: interval,
+		Host:     make(map[string]float64),
+		Devices:  make(map[string]map[string]float64),
+	}
+	if interval <= 0 {
+		return rates
+	}
+	secs := interval.Seconds()
+
+	deltas := make(map[string]int64, len(counters))
+	for _, c := range counters {
+		prevTable, curTable := prev.SNMP, cur.SNMP
+		if c.netstat {
+			prevTable, curTable = prev.Netstat, cur.Netstat
+		}
+		p, ok1 := prevTable[c.prefix][c.key]
+		v, ok2 := curTable[c.prefix][c.key]
+		// 内核不支持的计数或计数被重置时跳过
+		if !ok1 || !ok2 || v < p {
+			continue
+		}
+		deltas[c.field] = v - p
+		rates.Host[c.field+"_rate"] = float64(v-p) / secs
+	}
+	if out, ok := deltas["tcp_out_segs"]; ok && out > 0 {
+		rates.Host["tcp_retrans_ratio"] = float64(deltas["tcp_retrans_segs"]) / float64(out)
+	}
+
+	for name, d := range cur.Dev {
+		p, ok := prev.Dev[name]
+		if !ok {
+			continue
+		}
+		dev := make(map[string]float64)
+		addRate(dev, "rx_bytes_rate", p.RxBytes, d.RxBytes, secs)
+		addRate(dev, "rx_packets_rate", p.RxPackets, d.RxPackets, secs)
+		addRate(dev, "rx_errs_rate", p.RxErrs, d.RxErrs, secs)
+		addRate(dev, "rx_drop_rate", p.RxDrop, d.RxDrop, secs)
+		addRate(dev, "rx_fifo_rate", p.RxFifo, d.RxFifo, secs)
+		addRate(dev, "rx_frame_rate", p.RxFrame, d.RxFrame, secs)
+		addRate(dev, "tx_bytes_rate", p.TxBytes, d.TxBytes, secs)
+		addRate(dev, "tx_packets_rate", p.TxPackets, d.TxPackets, secs)
+		addRate(dev, "tx_errs_rate", p.TxErrs, d.TxErrs, secs)
+		addRate(dev, "tx_drop_rate", p.TxDrop, d.TxDrop, secs)
+		addRate(dev, "tx_fifo_rate", p.TxFifo, d.TxFifo, secs)
+		addRate(dev, "tx_colls_rate", p.TxColls, d.TxColls, secs)
+		addRate(dev, "tx_carrier_rate", p.TxCarrier, d.TxCarrier, secs)
+		rates.Devices[name] = dev
+	}
+	return rates
+}
+
+func addRate(m map[string]float64, field string, prev, cur uint64, secs float64) {
+	if cur < prev {
+		return
+	}
+	m[field] = float64(cur-prev) / secs
+}
@@ -0,0 +1,132 @@
+package hostnet
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const procRoot = "/proc"
+
+// DevCounters /proc/net/dev 中单个网卡的计数
+type DevCounters struct {
+	RxBytes   uint64
+	RxPackets uint64
+	RxErrs    uint64
+	RxDrop    uint64
+	RxFifo    uint64
+	RxFrame   uint64
+	TxBytes   uint64
+	TxPackets uint64
+	TxErrs    uint64
+	TxDrop    uint64
+	TxFifo    uint64
+	TxColls   uint64
+	TxCarrier uint64
+}
+
+// readKeyValueTable 解析 /proc/net/snmp 和 /proc/net/netstat 格式：
+// 每个协议两行，第一行为字段名，第二行为对应的值
+func readKeyValueTable(path string) (map[string]map[string]int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	table := make(map[string]map[string]int64)
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		header := strings.Fields(scanner.Text())
+		if !scanner.Scan() {
+			break
+		}
+		values := strings.Fields(scanner.Text())
+		if len(header) == 0 || len(header) != len(values) || header[0] != values[0] {
+			return nil, fmt.Errorf("malformed %s near %q", path, header)
+		}
+
+		prefix := strings.TrimSuffix(header[0], ":")
+		row := make(map[string]int64, len(header)-1)
+		for i := 1; i < len(header); i++ {
+			v, err := strconv.ParseInt(values[i], 10, 64)
+			if err != nil {
+				// 部分计数超出int64范围时按无符号解析
+				u, uerr := strconv.ParseUint(values[i], 10, 64)
+				if uerr != nil {
+					return nil, fmt.Errorf("invalid value %q for %s.%s", values[i], prefix, header[i])
+				}
+				v = int64(u)
+			}
+			row[header[i]] = v
+		}
+		table[prefix] = row
+	}
+	return table, scanner.Err()
+}
+
+// readNetDev 解析 /proc/net/dev
+func readNetDev(path string) (map[string]DevCounters, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	devs := make(map[string]DevCounters)
+	scanner := bufio.NewScanner(f)
+	for line := 0; scanner.Scan(); line++ {
+		// 跳过两行表头
+		if line < 2 {
+			continue
+		}
+		name, rest, ok := strings.Cut(scanner.Text(), ":")
+		if !ok {
+			continue
+		}
+		fields := strings.Fields(rest)
+		if len(fields) < 16 {
+			return nil, fmt.Errorf("malformed %s line for %s", path, strings.TrimSpace(name))
+		}
+		v := make([]uint64, 16)
+		for i := range v {
+			v[i], err = strconv.ParseUint(fields[i], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid value %q in %s", fields[i], path)
+			}
+		}
+		devs[strings.TrimSpace(name)] = DevCounters{
+			RxBytes:   v[0],
+			RxPackets: v[1],
+			RxErrs:    v[2],
+			RxDrop:    v[3],
+			RxFifo:    v[4],
+			RxFrame:   v[5],
+			TxBytes:   v[8],
+			TxPackets: v[9],
+			TxErrs:    v[10],
+			TxDrop:    v[11],
+			TxFifo:    v[12],
+			TxColls:   v[13],
+			TxCarrier: v[14],
+		}
+	}
+	return devs, scanner.Err()
+}
+
+// readUint 读取只包含一个整数的文件
+func readUint(path string) (uint64, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+}
+
+func procPath(elem ...string) string {
+	return filepath.Join(append([]string{procRoot}, elem...)...)
+}
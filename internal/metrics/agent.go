@@ -0,0 +1,27 @@
+package metrics
+
+// agent自身指标
+var (
+	TasksReceived = NewCounterVec("netdetect_agent_tasks_received_total",
+		"Task messages received from Kafka or the local scheduler.", "task")
+	TasksSkipped = NewCounterVec("netdetect_agent_tasks_skipped_total",
+		"Task messages dropped before execution.", "task", "reason")
+	TasksRunning = NewGaugeVec("netdetect_agent_tasks_running",
+		"Task executions currently in progress.")
+	TaskExecutions = NewCounterVec("netdetect_agent_task_executions_total",
+		"Finished task executions.", "task", "result")
+	TaskDuration = NewHistogramVec("netdetect_agent_task_duration_seconds",
+		"Task execution duration in seconds.", defaultBuckets, "task")
+	TaskTargetErrors = NewCounterVec("netdetect_agent_task_target_errors_total",
+		"Probe targets that reported an error.", "task")
+	StorageWrites = NewCounterVec("netdetect_agent_storage_writes_total",
+		"Result storage writes.", "result")
+	StorageLines = NewCounterVec("netdetect_agent_storage_lines_total",
+		"Result lines passed to storage.")
+	ConsumerLag = NewGaugeVec("netdetect_agent_consumer_lag",
+		"Messages between the committed group offset and the partition high water mark.", "topic", "partition")
+	BuildInfo = NewGaugeVec("netdetect_agent_build_info",
+		"Agent version, always 1.", "version")
+	StartTime = NewGaugeVec("netdetect_agent_start_time_seconds",
+		"Agent start time in unix seconds.")
+)
@@ -0,0 +1,210 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// 指标类型
+const (
+	typeCounter   = "counter"
+	typeGauge     = "gauge"
+	typeHistogram = "histogram"
+)
+
+// defaultBuckets 任务耗时直方图的分桶，单位秒
+var defaultBuckets = []float64{0.1, 0.5, 1, 2.5, 5, 10, 30, 60, 120, 300}
+
+var (
+	registryMu sync.Mutex
+	registry   []*vec
+)
+
+// vec 一组同名不同标签的指标，按Prometheus文本格式输出
+type vec struct {
+	name    string
+	help    string
+	typ     string
+	labels  []string
+	buckets []float64
+
+	mu     sync.Mutex
+	series map[string]*series
+}
+
+type series struct {
+	labelValues []string
+	value       float64
+	// 直方图
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+func newVec(name, help, typ string, buckets []float64, labels []string) *vec {
+	v := &vec{
+		name:    name,
+		help:    help,
+		typ:     typ,
+		labels:  labels,
+		buckets: buckets,
+		series:  make(map[string]*series),
+	}
+	registryMu.Lock()
+	registry = append(registry, v)
+	registryMu.Unlock()
+	return v
+}
+
+func (v *vec) get(labelValues []string) *series {
+	if len(labelValues) != len(v.labels) {
+		panic(fmt.Sprintf("metric %s: expected %d label values, got %d", v.name, len(v.labels), len(labelValues)))
+	}
+	key := strings.Join(labelValues, "\xff")
+	s, ok := v.series[key]
+	if !ok {
+		s = &series{labelValues: append([]string(nil), labelValues...)}
+		if v.typ == typeHistogram {
+			s.counts = make([]uint64, len(v.buckets))
+		}
+		v.series[key] = s
+	}
+	return s
+}
+
+// CounterVec 只增计数器
+type CounterVec struct{ v *vec }
+
+func NewCounterVec(name, help string, labels ...string) *CounterVec {
+	return &CounterVec{v: newVec(name, help, typeCounter, nil, labels)}
+}
+
+func (c *CounterVec) Add(delta float64, labelValues ...string) {
+	c.v.mu.Lock()
+	c.v.get(labelValues).value += delta
+	c.v.mu.Unlock()
+}
+
+func (c *CounterVec) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// GaugeVec 可增减的瞬时值
+type GaugeVec struct{ v *vec }
+
+func NewGaugeVec(name, help string, labels ...string) *GaugeVec {
+	return &GaugeVec{v: newVec(name, help, typeGauge, nil, labels)}
+}
+
+func (g *GaugeVec) Set(value float64, labelValues ...string) {
+	g.v.mu.Lock()
+	g.v.get(labelValues).value = value
+	g.v.mu.Unlock()
+}
+
+func (g *GaugeVec) Add(delta float64, labelValues ...string) {
+	g.v.mu.Lock()
+	g.v.get(labelValues).value += delta
+	g.v.mu.Unlock()
+}
+
+// HistogramVec 直方图
+type HistogramVec struct{ v *vec }
+
+func NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	return &HistogramVec{v: newVec(name, help, typeHistogram, buckets, labels)}
+}
+
+func (h *HistogramVec) Observe(value float64, labelValues ...string) {
+	h.v.mu.Lock()
+	defer h.v.mu.Unlock()
+	s := h.v.get(labelValues)
+	for i, le := range h.v.buckets {
+		if value <= le {
+			s.counts[i]++
+		}
+	}
+	s.sum += value
+	s.count++
+}
+
+// writeAll 按注册顺序输出所有指标
+func writeAll(w io.Writer) {
+	registryMu.Lock()
+	vecs := append([]*vec(nil), registry...)
+	registryMu.Unlock()
+	for _, v := range vecs {
+		v.write(w)
+	}
+}
+
+func (v *vec) write(w io.Writer) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if len(v.series) == 0 {
+		return
+	}
+
+	keys := make([]string, 0, len(v.series))
+	for k := range v.series {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", v.name, v.help, v.name, v.typ)
+	for _, k := range keys {
+		s := v.series[k]
+		if v.typ != typeHistogram {
+			fmt.Fprintf(w, "%s%s %s\n", v.name, formatLabels(v.labels, s.labelValues, "", ""), formatValue(s.value))
+			continue
+		}
+		for i, le := range v.buckets {
+			fmt.Fprintf(w, "%s_bucket%s %d\n", v.name, formatLabels(v.labels, s.labelValues, "le", formatValue(le)), s.counts[i])
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", v.name, formatLabels(v.labels, s.labelValues, "le", "+Inf"), s.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", v.name, formatLabels(v.labels, s.labelValues, "", ""), formatValue(s.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", v.name, formatLabels(v.labels, s.labelValues, "", ""), s.count)
+	}
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// formatLabels 输出标签，extraName不为空时追加一个标签
+func formatLabels(names, values []string, extraName, extraValue string) string {
+	if len(names) == 0 && extraName == "" {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, name := range names {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(&b, "%s=\"%s\"", name, labelEscaper.Replace(values[i]))
+	}
+	if extraName != "" {
+		if len(names) > 0 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(&b, "%s=\"%s\"", extraName, extraValue)
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+func formatValue(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
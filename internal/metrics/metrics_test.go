@@ -0,0 +1,90 @@
+package metrics
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func render(v *vec) string {
+	var b strings.Builder
+	v.write(&b)
+	return b.String()
+}
+
+func TestCounterAndGaugeExposition(t *testing.T) {
+	c := NewCounterVec("test_requests_total", "Requests.", "code", "path")
+	c.Inc("200", "/b")
+	c.Add(2, "200", "/a")
+	c.Inc("500", `/q"x\y`+"\n")
+
+	want := `# HELP test_requests_total Requests.
+# TYPE test_requests_total counter
+test_requests_total{code="200",path="/a"} 2
+test_requests_total{code="200",path="/b"} 1
+test_requests_total{code="500",path="/q\"x\\y\n"} 1
+`
+	if got := render(c.v); got != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", got, want)
+	}
+
+	g := NewGaugeVec("test_temperature", "Temperature.")
+	if got := render(g.v); got != "" {
+		t.Fatalf("empty vec rendered %q", got)
+	}
+	g.Set(1.5)
+	g.Add(-0.25)
+	want = `# HELP test_temperature Temperature.
+# TYPE test_temperature gauge
+test_temperature 1.25
+`
+	if got := render(g.v); got != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestHistogramExposition(t *testing.T) {
+	h := NewHistogramVec("test_duration_seconds", "Duration.", []float64{0.5, 1}, "task")
+	h.Observe(0.2, "ping")
+	h.Observe(0.7, "ping")
+	h.Observe(3, "ping")
+
+	// 分桶计数为累计值，+Inf桶等于总数
+	want := `# HELP test_duration_seconds Duration.
+# TYPE test_duration_seconds histogram
+test_duration_seconds_bucket{task="ping",le="0.5"} 1
+test_duration_seconds_bucket{task="ping",le="1"} 2
+test_duration_seconds_bucket{task="ping",le="+Inf"} 3
+test_duration_seconds_sum{task="ping"} 3.9
+test_duration_seconds_count{task="ping"} 3
+`
+	if got := render(h.v); got != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestLabelCountMismatchPanics(t *testing.T) {
+	g := NewGaugeVec("test_mismatch", "Mismatch.", "a")
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected panic")
+		}
+	}()
+	g.Set(1, "x", "y")
+}
+
+func TestFormatValue(t *testing.T) {
+	cases := map[float64]string{
+		0:            "0",
+		1e-3:         "0.001",
+		12345678:     "1.2345678e+07",
+		math.Inf(1):  "+Inf",
+		math.Inf(-1): "-Inf",
+		math.NaN():   "NaN",
+	}
+	for v, want := range cases {
+		if got := formatValue(v); got != want {
+			t.Errorf("formatValue(%v) = %q, want %q", v, got, want)
+		}
+	}
+}
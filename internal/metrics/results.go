@@ -0,0 +1,229 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultResultTTL = 10 * time.Minute
+
+// ResultGauges 保存最近一次探测结果，每个数值field按VictoriaMetrics的命名方式
+// 输出为 <measurement>_<field> gauge，tag作为标签，超过ttl未更新的序列不再输出
+type ResultGauges struct {
+	ttl time.Duration
+
+	mu     sync.Mutex
+	series map[string]*resultSeries
+}
+
+type resultSeries struct {
+	name    string
+	labels  string
+	value   float64
+	updated time.Time
+}
+
+func NewResultGauges(ttl time.Duration) *ResultGauges {
+	if ttl <= 0 {
+		ttl = defaultResultTTL
+	}
+	return &ResultGauges{ttl: ttl, series: make(map[string]*resultSeries)}
+}
+
+// Update 解析Influx行协议并更新对应序列，无法解析的行和非数值field忽略
+func (r *ResultGauges) Update(lines []string) {
+	now := time.Now()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, line := range lines {
+		for _, l := range strings.Split(line, "\n") {
+			measurement, tags, fields, ok := parseLine(l)
+			if !ok {
+				continue
+			}
+			labels := resultLabels(tags)
+			for _, f := range fields {
+				value, ok := parseFieldValue(f[1])
+				if !ok {
+					continue
+				}
+				name := sanitizeName(measurement + "_" + f[0])
+				key := name + labels
+				s, exists := r.series[key]
+				if !exists {
+					s = &resultSeries{name: name, labels: labels}
+					r.series[key] = s
+				}
+				s.value = value
+				s.updated = now
+			}
+		}
+	}
+}
+
+// write 按名称输出未过期的序列，同时清理过期序列
+func (r *ResultGauges) write(w io.Writer) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	expireBefore := time.Now().Add(-r.ttl)
+	keys := make([]string, 0, len(r.series))
+	for k, s := range r.series {
+		if s.updated.Before(expireBefore) {
+			delete(r.series, k)
+			continue
+		}
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := r.series[keys[i]], r.series[keys[j]]
+		if a.name != b.name {
+			return a.name < b.name
+		}
+		return a.labels < b.labels
+	})
+
+	last := ""
+	for _, k := range keys {
+		s := r.series[k]
+		if s.name != last {
+			fmt.Fprintf(w, "# TYPE %s %s\n", s.name, typeGauge)
+			last = s.name
+		}
+		fmt.Fprintf(w, "%s%s %s\n", s.name, s.labels, formatValue(s.value))
+	}
+}
+
+// resultLabels 按标签名排序输出，保证同一组tag得到相同的序列key
+func resultLabels(tags [][2]string) string {
+	if len(tags) == 0 {
+		return ""
+	}
+	sort.Slice(tags, func(i, j int) bool { return tags[i][0] < tags[j][0] })
+	names := make([]string, 0, len(tags))
+	values := make([]string, 0, len(tags))
+	for _, t := range tags {
+		name := sanitizeName(t[0])
+		// 同名标签只保留第一个
+		if len(names) > 0 && names[len(names)-1] == name {
+			continue
+		}
+		names = append(names, name)
+		values = append(values, t[1])
+	}
+	return formatLabels(names, values, "", "")
+}
+
+// sanitizeName 将指标名和标签名中的非法字符替换为下划线
+func sanitizeName(name string) string {
+	b := []byte(name)
+	for i, c := range b {
+		if c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || i > 0 && c >= '0' && c <= '9' {
+			continue
+		}
+		b[i] = '_'
+	}
+	return string(b)
+}
+
+// parseFieldValue 解析数值、整数(带i/u后缀)和布尔类型的field，字符串field返回false
+func parseFieldValue(v string) (float64, bool) {
+	if v == "" || v[0] == '"' {
+		return 0, false
+	}
+	switch v {
+	case "t", "T", "true", "True", "TRUE":
+		return 1, true
+	case "f", "F", "false", "False", "FALSE":
+		return 0, true
+	}
+	if last := v[len(v)-1]; last == 'i' || last == 'u' {
+		n, err := strconv.ParseInt(v[:len(v)-1], 10, 64)
+		return float64(n), err == nil
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	return f, err == nil
+}
+
+// parseLine 解析一行Influx行协议，返回measurement、tag和field的键值对，
+// field值保持原始形式
+func parseLine(line string) (string, [][2]string, [][2]string, bool) {
+	line = strings.TrimSpace(line)
+	if line == "" || line[0] == '#' {
+		return "", nil, nil, false
+	}
+	sections := splitUnescaped(line, ' ', 3)
+	if len(sections) < 2 {
+		return "", nil, nil, false
+	}
+	head := splitUnescaped(sections[0], ',', -1)
+	measurement := unescape(head[0])
+	if measurement == "" {
+		return "", nil, nil, false
+	}
+
+	var tags [][2]string
+	for _, kv := range head[1:] {
+		k, v, ok := splitKeyValue(kv)
+		if !ok {
+			return "", nil, nil, false
+		}
+		tags = append(tags, [2]string{unescape(k), unescape(v)})
+	}
+	var fields [][2]string
+	for _, kv := range splitUnescaped(sections[1], ',', -1) {
+		k, v, ok := splitKeyValue(kv)
+		if !ok {
+			return "", nil, nil, false
+		}
+		fields = append(fields, [2]string{unescape(k), v})
+	}
+	return measurement, tags, fields, len(fields) > 0
+}
+
+// splitUnescaped 按未转义且不在双引号内的sep切分，n<0时不限制段数
+func splitUnescaped(s string, sep byte, n int) []string {
+	var parts []string
+	quoted := false
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '\\':
+			i++
+		case c == '"':
+			quoted = !quoted
+		case c == sep && !quoted:
+			if n > 0 && len(parts) == n-1 {
+				return append(parts, s[start:])
+			}
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+func splitKeyValue(kv string) (string, string, bool) {
+	for i := 0; i < len(kv); i++ {
+		switch kv[i] {
+		case '\\':
+			i++
+		case '=':
+			return kv[:i], kv[i+1:], i > 0
+		}
+	}
+	return "", "", false
+}
+
+var lineUnescaper = strings.NewReplacer(`\,`, ",", `\=`, "=", `\ `, " ", `\\`, `\`)
+
+func unescape(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	return lineUnescaper.Replace(s)
+}
@@ -0,0 +1,144 @@
+package metrics
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseLine(t *testing.T) {
+	cases := []struct {
+		name        string
+		line        string
+		ok          bool
+		measurement string
+		tags        [][2]string
+		fields      [][2]string
+	}{
+		{
+			name:        "tags and fields",
+			line:        "ping,source_ip=10.0.0.1,target_ip=10.0.0.2 packets_sent=3i,rtt_avg=1.5 1700000000000000000",
+			ok:          true,
+			measurement: "ping",
+			tags:        [][2]string{{"source_ip", "10.0.0.1"}, {"target_ip", "10.0.0.2"}},
+			fields:      [][2]string{{"packets_sent", "3i"}, {"rtt_avg", "1.5"}},
+		},
+		{
+			name:        "escaped tag values",
+			line:        `tls,sni=a\,b\ c\=d success=true`,
+			ok:          true,
+			measurement: "tls",
+			tags:        [][2]string{{"sni", "a,b c=d"}},
+			fields:      [][2]string{{"success", "true"}},
+		},
+		{
+			name:        "quoted string field with separators",
+			line:        `http,target=x error="dial tcp: a, b=c \"q\"",success=false 1`,
+			ok:          true,
+			measurement: "http",
+			tags:        [][2]string{{"target", "x"}},
+			fields:      [][2]string{{"error", `"dial tcp: a, b=c \"q\""`}, {"success", "false"}},
+		},
+		{
+			name:        "no tags",
+			line:        "cpu value=1",
+			ok:          true,
+			measurement: "cpu",
+			fields:      [][2]string{{"value", "1"}},
+		},
+		{name: "empty", line: "  "},
+		{name: "comment", line: "# comment"},
+		{name: "no fields", line: "cpu,host=a"},
+		{name: "bad tag", line: "cpu,host value=1"},
+		{name: "bad field", line: "cpu value 1"},
+	}
+	for _, c := range cases {
+		measurement, tags, fields, ok := parseLine(c.line)
+		if ok != c.ok {
+			t.Errorf("%s: ok = %v, want %v", c.name, ok, c.ok)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		if measurement != c.measurement || !reflect.DeepEqual(tags, c.tags) || !reflect.DeepEqual(fields, c.fields) {
+			t.Errorf("%s: got %q %q %q", c.name, measurement, tags, fields)
+		}
+	}
+}
+
+func TestParseFieldValue(t *testing.T) {
+	cases := []struct {
+		in    string
+		value float64
+		ok    bool
+	}{
+		{"1.5", 1.5, true},
+		{"-2e3", -2000, true},
+		{"42i", 42, true},
+		{"7u", 7, true},
+		{"t", 1, true},
+		{"false", 0, true},
+		{`"str"`, 0, false},
+		{"", 0, false},
+		{"abc", 0, false},
+	}
+	for _, c := range cases {
+		value, ok := parseFieldValue(c.in)
+		if ok != c.ok || (ok && value != c.value) {
+			t.Errorf("parseFieldValue(%q) = %v, %v, want %v, %v", c.in, value, ok, c.value, c.ok)
+		}
+	}
+}
+
+func TestResultGauges(t *testing.T) {
+	r := NewResultGauges(time.Minute)
+	r.Update([]string{
+		"ping,target_ip=10.0.0.2,source_ip=10.0.0.1 rtt_avg=1.5,packets_loss=0i,error=\"x\" 1",
+		"ping,source_ip=10.0.0.1,target_ip=10.0.0.3 rtt_avg=2 1\nhttp-probe,url=a success=true 1",
+		"garbage",
+	})
+	// 同一序列的新结果覆盖旧值，tag顺序不影响序列
+	r.Update([]string{"ping,source_ip=10.0.0.1,target_ip=10.0.0.2 rtt_avg=3 2"})
+
+	var b strings.Builder
+	r.write(&b)
+	want := `# TYPE http_probe_success gauge
+http_probe_success{url="a"} 1
+# TYPE ping_packets_loss gauge
+ping_packets_loss{source_ip="10.0.0.1",target_ip="10.0.0.2"} 0
+# TYPE ping_rtt_avg gauge
+ping_rtt_avg{source_ip="10.0.0.1",target_ip="10.0.0.2"} 3
+ping_rtt_avg{source_ip="10.0.0.1",target_ip="10.0.0.3"} 2
+`
+	if got := b.String(); got != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", got, want)
+	}
+
+	// 超过ttl未更新的序列不再输出并被清理
+	r.mu.Lock()
+	for _, s := range r.series {
+		s.updated = s.updated.Add(-2 * time.Minute)
+	}
+	r.mu.Unlock()
+	b.Reset()
+	r.write(&b)
+	if b.Len() != 0 || len(r.series) != 0 {
+		t.Fatalf("expired series written: %q", b.String())
+	}
+}
+
+func TestSanitizeName(t *testing.T) {
+	cases := map[string]string{
+		"http-probe_rtt": "http_probe_rtt",
+		"9lives":         "_lives",
+		"a.b:c":          "a_b_c",
+		"ok_1":           "ok_1",
+	}
+	for in, want := range cases {
+		if got := sanitizeName(in); got != want {
+			t.Errorf("sanitizeName(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package metrics
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// HealthCheck 返回nil表示健康
+type HealthCheck func() error
+
+// Server agent的HTTP监听，/metrics输出Prometheus文本格式指标，/healthz返回各项健康检查结果
+type Server struct {
+	addr    string
+	results *ResultGauges
+
+	mu     sync.Mutex
+	checks map[string]HealthCheck
+	server *http.Server
+}
+
+// NewServer results不为空时/metrics同时输出最新探测结果
+func NewServer(addr string, results *ResultGauges) *Server {
+	return &Server{
+		addr:    addr,
+		results: results,
+		checks:  make(map[string]HealthCheck),
+	}
+}
+
+// AddHealthCheck 注册健康检查，任一检查失败时/healthz返回503
+func (s *Server) AddHealthCheck(name string, check HealthCheck) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.checks[name] = check
+}
+
+// Start 监听并处理请求，直到Stop被调用
+func (s *Server) Start() error {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", s.handleMetrics)
+	mux.HandleFunc("/healthz", s.handleHealth)
+
+	listener, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return err
+	}
+	server := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	s.mu.Lock()
+	s.server = server
+	s.mu.Unlock()
+	log.Printf("Metrics server listening on %s", listener.Addr())
+
+	if err := server.Serve(listener); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+func (s *Server) Stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.server != nil {
+		s.server.Close()
+	}
+}
+
+func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	writeAll(w)
+	if s.results != nil {
+		s.results.write(w)
+	}
+}
+
+type healthResponse struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks"`
+}
+
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	names := make([]string, 0, len(s.checks))
+	for name := range s.checks {
+		names = append(names, name)
+	}
+	checks := make(map[string]HealthCheck, len(s.checks))
+	for name, check := range s.checks {
+		checks[name] = check
+	}
+	s.mu.Unlock()
+	sort.Strings(names)
+
+	resp := healthResponse{Status: "ok", Checks: make(map[string]string, len(names))}
+	code := http.StatusOK
+	for _, name := range names {
+		if err := checks[name](); err != nil {
+			resp.Checks[name] = err.Error()
+			resp.Status = "unhealthy"
+			code = http.StatusServiceUnavailable
+			continue
+		}
+		resp.Checks[name] = "ok"
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,64 @@
+package metrics
+
+import (
+	"fmt"
+	"sync"
+
+	"net_detect/internal/storage"
+)
+
+// unhealthyFailures 连续写入失败达到该次数时认为存储不健康，偶发的失败不影响健康检查
+const unhealthyFailures = 3
+
+// Storage 包装结果存储，统计写入结果并记录连续写入失败次数，
+// results不为空时同时以gauge形式保存最新结果
+type Storage struct {
+	storage storage.ResultStorage
+	results *ResultGauges
+
+	mu       sync.Mutex
+	lastErr  error
+	failures int
+}
+
+func WrapStorage(storage storage.ResultStorage, results *ResultGauges) *Storage {
+	return &Storage{storage: storage, results: results}
+}
+
+func (s *Storage) Store(lines []string) error {
+	// 推送失败时仍更新gauge，Prometheus抓取不依赖推送链路
+	if s.results != nil {
+		s.results.Update(lines)
+	}
+	err := s.storage.Store(lines)
+	if err != nil {
+		StorageWrites.Inc("error")
+	} else {
+		StorageWrites.Inc("success")
+	}
+	StorageLines.Add(float64(len(lines)))
+
+	s.mu.Lock()
+	s.lastErr = err
+	if err != nil {
+		s.failures++
+	} else {
+		s.failures = 0
+	}
+	s.mu.Unlock()
+	return err
+}
+
+func (s *Storage) Close() error {
+	return s.storage.Close()
+}
+
+// Healthy 连续写入失败未达到unhealthyFailures次时返回nil
+func (s *Storage) Healthy() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.failures < unhealthyFailures {
+		return nil
+	}
+	return fmt.Errorf("%d consecutive writes failed: %v", s.failures, s.lastErr)
+}
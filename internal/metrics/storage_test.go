@@ -0,0 +1,34 @@
+package metrics
+
+import (
+	"errors"
+	"testing"
+)
+
+type fakeStorage struct{ err error }
+
+func (f *fakeStorage) Store(lines []string) error { return f.err }
+func (f *fakeStorage) Close() error               { return nil }
+
+func TestStorageHealthyAfterConsecutiveFailures(t *testing.T) {
+	backend := &fakeStorage{err: errors.New("connection refused")}
+	s := WrapStorage(backend, nil)
+
+	for i := 1; i < unhealthyFailures; i++ {
+		s.Store([]string{"m v=1"})
+		if err := s.Healthy(); err != nil {
+			t.Fatalf("unhealthy after %d failures: %v", i, err)
+		}
+	}
+	s.Store([]string{"m v=1"})
+	if err := s.Healthy(); err == nil {
+		t.Fatalf("healthy after %d failures", unhealthyFailures)
+	}
+
+	// 一次成功写入后恢复
+	backend.err = nil
+	s.Store([]string{"m v=1"})
+	if err := s.Healthy(); err != nil {
+		t.Fatalf("unhealthy after success: %v", err)
+	}
+}
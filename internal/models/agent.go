@@ -0,0 +1,48 @@
+package models
+
+import "time"
+
+// AgentHeartbeat agent定期上报的心跳
+type AgentHeartbeat struct {
+	NodeName  string    `json:"nodeName"`
+	HostName  string    `json:"hostName"`
+	Version   string    `json:"version"`
+	IPs       []string  `json:"ips"`
+	Tasks     []string  `json:"tasks"` // 已注册的任务类型
+	StartedAt time.Time `json:"startedAt"`
+	Uptime    float64   `json:"uptime"` // 秒
+	Interval  float64   `json:"interval"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// AgentInfo 控制器记录的agent状态，超过判定时间未收到心跳时Stale为true
+type AgentInfo struct {
+	AgentHeartbeat
+	LastSeen time.Time `json:"lastSeen"`
+	Stale    bool      `json:"stale"`
+}
+
+// TaskStatus agent每次执行任务后上报的状态。Error为执行失败的原因，
+// Errors和TargetError为探测失败的目标数及其中一个目标的失败原因
+type TaskStatus struct {
+	TaskName    string    `json:"taskName"`
+	MetricName  string    `json:"metricName"`
+	NodeName    string    `json:"nodeName"`
+	HostName    string    `json:"hostName"`
+	Start       time.Time `json:"start"`
+	End         time.Time `json:"end"`
+	Duration    float64   `json:"duration"` // 毫秒
+	Targets     int       `json:"targets"`
+	Errors      int       `json:"errors"`
+	Error       string    `json:"error,omitempty"`
+	TargetError string    `json:"targetError,omitempty"`
+}
+
+// TaskDetail 任务定义及各节点最近一次执行状态。任一节点最近一次执行出错或所有目标都失败时
+// Failing为true，任一节点有目标失败时Degraded为true
+type TaskDetail struct {
+	Task
+	LastRuns map[string]TaskStatus `json:"lastRuns"`
+	Failing  bool                  `json:"failing"`
+	Degraded bool                  `json:"degraded"`
+}
@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+// DNSProbeTarget DNS解析探测目标
+type DNSProbeTarget struct {
+	Resolver string            `json:"resolver"`           // 解析器地址，ip或ip:port，默认53端口
+	Name     string            `json:"name"`               // 查询的域名
+	Type     string            `json:"type"`               // 记录类型：A/AAAA/CNAME/SRV/TXT
+	Protocol string            `json:"protocol,omitempty"` // udp或tcp，默认udp
+	Expected []string          `json:"expected,omitempty"` // 期望的应答集合
+	Timeout  time.Duration     `json:"timeout,omitempty"`
+	NodeName string            `json:"nodeName"`
+	HostName string            `json:"hostName"`
+	Tags     map[string]string `json:"tags,omitempty"` // 附加标签
+}
+
+// DNSProbeResult DNS解析探测结果
+type DNSProbeResult struct {
+	SourceIP    string            `json:"sourceIp"`
+	ResolverIP  string            `json:"resolverIp"`
+	TargetNode  string            `json:"targetNode"`
+	TargetHost  string            `json:"targetHost"`
+	Name        string            `json:"name"`
+	Type        string            `json:"type"`
+	Protocol    string            `json:"protocol"`
+	Tags        map[string]string `json:"tags,omitempty"`
+	Latency     float64           `json:"latency"` // 毫秒
+	Rcode       int               `json:"rcode"`
+	RcodeName   string            `json:"rcodeName"`
+	AnswerCount int               `json:"answerCount"`
+	Answers     []string          `json:"answers"` // 与查询类型一致的应答
+	Truncated   bool              `json:"truncated"`
+	Match       bool              `json:"match"`
+	Error       string            `json:"error,omitempty"`
+	Timestamp   time.Time         `json:"timestamp"`
+}
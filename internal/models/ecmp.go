@@ -0,0 +1,50 @@
+package models
+
+import "time"
+
+// ECMPTarget 多流探测目标，每条流使用不同的源端口（IPv6可同时使用不同的流标签），
+// 使探测报文被哈希到不同的ECMP路径上
+type ECMPTarget struct {
+	IP            string            `json:"ip"`
+	Port          int               `json:"port,omitempty"`          // 目的端口，udp默认为反射端端口，tcp必填
+	Protocol      string            `json:"protocol,omitempty"`      // udp或tcp，默认udp
+	Flows         int               `json:"flows,omitempty"`         // 流数量，默认16
+	SrcPortBase   int               `json:"srcPortBase,omitempty"`   // 第i条流的源端口为SrcPortBase+i，为0时由系统分配
+	FlowLabelBase uint32            `json:"flowLabelBase,omitempty"` // 仅IPv6，第i条流的流标签为FlowLabelBase+i，为0时由内核生成
+	Count         int               `json:"count,omitempty"`         // 每条流的探测次数，默认使用ping配置
+	Interval      time.Duration     `json:"interval,omitempty"`
+	Timeout       time.Duration     `json:"timeout,omitempty"`
+	NodeName      string            `json:"nodeName"`
+	HostName      string            `json:"hostName"`
+	Tags          map[string]string `json:"tags,omitempty"` // 附加标签
+}
+
+// ECMPFlowResult 单条流的探测结果，时间单位毫秒
+type ECMPFlowResult struct {
+	FlowID      int     `json:"flowId"`
+	SrcPort     int     `json:"srcPort"`
+	FlowLabel   uint32  `json:"flowLabel,omitempty"`
+	PacketsSent int     `json:"packetsSent"`
+	PacketsRecv int     `json:"packetsRecv"`
+	MinRtt      float64 `json:"minRtt"`
+	MaxRtt      float64 `json:"maxRtt"`
+	AvgRtt      float64 `json:"avgRtt"`
+	StdDevRtt   float64 `json:"stdDevRtt"`
+	Jitter      float64 `json:"jitter"`
+	Error       string  `json:"error,omitempty"`
+}
+
+// ECMPResult 多流探测结果
+type ECMPResult struct {
+	SourceIP   string            `json:"sourceIp"`
+	TargetIP   string            `json:"targetIp"`
+	TargetPort int               `json:"targetPort"`
+	TargetNode string            `json:"targetNode"`
+	TargetHost string            `json:"targetHost"`
+	Protocol   string            `json:"protocol"`
+	Tags       map[string]string `json:"tags,omitempty"`
+	Flows      []ECMPFlowResult  `json:"flows"`
+	IPVersion  string            `json:"ipVersion"`
+	Error      string            `json:"error,omitempty"`
+	Timestamp  time.Time         `json:"timestamp"`
+}
@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+// GRPCHealthTarget gRPC健康检查目标，调用 grpc.health.v1.Health/Check
+type GRPCHealthTarget struct {
+	Address    string            `json:"address"`              // host:port
+	IP         string            `json:"ip,omitempty"`         // 指定连接的IP，跳过DNS解析
+	Service    string            `json:"service,omitempty"`    // 服务名，为空表示检查整个服务端
+	TLS        bool              `json:"tls,omitempty"`        // 使用TLS连接
+	ServerName string            `json:"serverName,omitempty"` // TLS校验的服务名，默认使用address中的host
+	Insecure   bool              `json:"insecure,omitempty"`   // 跳过证书校验
+	Timeout    time.Duration     `json:"timeout,omitempty"`
+	NodeName   string            `json:"nodeName"`
+	HostName   string            `json:"hostName"`
+	Tags       map[string]string `json:"tags,omitempty"` // 附加标签
+}
+
+// GRPCHealthResult gRPC健康检查结果，耗时单位毫秒
+type GRPCHealthResult struct {
+	SourceIP    string            `json:"sourceIp"`
+	TargetIP    string            `json:"targetIp"`
+	TargetNode  string            `json:"targetNode"`
+	TargetHost  string            `json:"targetHost"`
+	Address     string            `json:"address"`
+	Service     string            `json:"service"`
+	Tags        map[string]string `json:"tags,omitempty"`
+	Status      string            `json:"status"` // SERVING/NOT_SERVING/SERVICE_UNKNOWN/UNKNOWN
+	Serving     bool              `json:"serving"`
+	Code        string            `json:"code"` // gRPC状态码，如OK/Unavailable/DeadlineExceeded
+	ConnectTime float64           `json:"connectTime"`
+	Latency     float64           `json:"latency"` // 从发起调用到收到应答的总耗时
+	Error       string            `json:"error,omitempty"`
+	Timestamp   time.Time         `json:"timestamp"`
+}
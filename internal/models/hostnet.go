@@ -0,0 +1,8 @@
+package models
+
+// HostNetOptions 本机网络计数采集参数，可省略
+type HostNetOptions struct {
+	Devices         []string          `json:"devices,omitempty"`         // 只输出指定网卡，默认全部
+	IncludeLoopback bool              `json:"includeLoopback,omitempty"` // 是否输出lo网卡
+	Tags            map[string]string `json:"tags,omitempty"`            // 附加标签
+}
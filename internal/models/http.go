@@ -0,0 +1,42 @@
+package models
+
+import "time"
+
+// HTTPProbeTarget HTTP(S)探测目标
+type HTTPProbeTarget struct {
+	URL            string            `json:"url"`
+	Method         string            `json:"method,omitempty"` // 默认GET
+	IP             string            `json:"ip,omitempty"`     // 指定连接的IP，跳过DNS解析
+	NodeName       string            `json:"nodeName"`
+	HostName       string            `json:"hostName"`
+	Headers        map[string]string `json:"headers,omitempty"`        // 请求头
+	ExpectStatus   []int             `json:"expectStatus,omitempty"`   // 期望状态码，默认200-399
+	BodyRegex      string            `json:"bodyRegex,omitempty"`      // 响应体需匹配的正则
+	RequireHeaders map[string]string `json:"requireHeaders,omitempty"` // 响应必须包含的头，值为正则，空表示仅要求存在
+	Timeout        time.Duration     `json:"timeout,omitempty"`
+	Insecure       bool              `json:"insecure,omitempty"` // 跳过证书校验
+	Tags           map[string]string `json:"tags,omitempty"`     // 附加标签
+}
+
+// HTTPProbeResult HTTP(S)探测结果，耗时单位毫秒
+type HTTPProbeResult struct {
+	SourceIP     string            `json:"sourceIp"`
+	TargetIP     string            `json:"targetIp"`
+	TargetNode   string            `json:"targetNode"`
+	TargetHost   string            `json:"targetHost"`
+	URL          string            `json:"url"`
+	Method       string            `json:"method"`
+	Tags         map[string]string `json:"tags,omitempty"`
+	DNSTime      float64           `json:"dnsTime"`
+	ConnectTime  float64           `json:"connectTime"`
+	TLSTime      float64           `json:"tlsTime"`
+	TTFB         float64           `json:"ttfb"`
+	TotalTime    float64           `json:"totalTime"`
+	StatusCode   int               `json:"statusCode"`
+	BodySize     int64             `json:"bodySize"`
+	StatusOK     bool              `json:"statusOk"`
+	BodyMatch    bool              `json:"bodyMatch"`
+	HeadersMatch bool              `json:"headersMatch"`
+	Error        string            `json:"error,omitempty"`
+	Timestamp    time.Time         `json:"timestamp"`
+}
@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// NTPTarget NTP服务器探测目标
+type NTPTarget struct {
+	Server   string            `json:"server"` // ip或ip:port，默认123端口
+	Timeout  time.Duration     `json:"timeout,omitempty"`
+	NodeName string            `json:"nodeName"`
+	HostName string            `json:"hostName"`
+	Tags     map[string]string `json:"tags,omitempty"` // 附加标签
+}
+
+// NTPResult NTP服务器探测结果，时间单位毫秒
+type NTPResult struct {
+	SourceIP   string            `json:"sourceIp"`
+	ServerIP   string            `json:"serverIp"`
+	TargetNode string            `json:"targetNode"`
+	TargetHost string            `json:"targetHost"`
+	Tags       map[string]string `json:"tags,omitempty"`
+	Offset     float64           `json:"offset"`
+	Delay      float64           `json:"delay"`
+	Stratum    int               `json:"stratum"`
+	Leap       int               `json:"leap"`
+	RefID      string            `json:"refId"`
+	Error      string            `json:"error,omitempty"`
+	Timestamp  time.Time         `json:"timestamp"`
+}
@@ -0,0 +1,50 @@
+package models
+
+import "time"
+
+// QUICProbeTarget QUIC握手及HTTP/3请求探测目标
+type QUICProbeTarget struct {
+	Address    string            `json:"address"`              // host:port，省略端口时为443
+	IP         string            `json:"ip,omitempty"`         // 指定连接的IP，跳过DNS解析
+	ServerName string            `json:"serverName,omitempty"` // SNI，默认使用address中的host
+	ALPN       []string          `json:"alpn,omitempty"`       // 默认h3
+	HTTP3      bool              `json:"http3,omitempty"`      // 握手后发送HTTP/3 GET请求
+	Path       string            `json:"path,omitempty"`       // 请求路径，默认/
+	ZeroRTT    bool              `json:"zeroRtt,omitempty"`    // 复用上次探测的会话票据尝试0-RTT
+	Insecure   bool              `json:"insecure,omitempty"`   // 跳过证书校验
+	Timeout    time.Duration     `json:"timeout,omitempty"`
+	NodeName   string            `json:"nodeName"`
+	HostName   string            `json:"hostName"`
+	Tags       map[string]string `json:"tags,omitempty"` // 附加标签
+}
+
+// QUICProbeResult QUIC探测结果，耗时单位毫秒
+type QUICProbeResult struct {
+	SourceIP      string            `json:"sourceIp"`
+	TargetIP      string            `json:"targetIp"`
+	TargetNode    string            `json:"targetNode"`
+	TargetHost    string            `json:"targetHost"`
+	Address       string            `json:"address"`
+	Tags          map[string]string `json:"tags,omitempty"`
+	HandshakeTime float64           `json:"handshakeTime"`
+	Version       string            `json:"version"` // 协商的QUIC版本
+	ALPN          string            `json:"alpn"`
+	Resumed       bool              `json:"resumed"` // TLS会话复用
+	Used0RTT      bool              `json:"used0Rtt"`
+	StatusCode    int               `json:"statusCode"`
+	RequestTime   float64           `json:"requestTime"` // 发出请求到收到响应头
+	TotalTime     float64           `json:"totalTime"`   // 发起连接到读完响应体
+	ErrorClass    string            `json:"errorClass,omitempty"`
+	Error         string            `json:"error,omitempty"`
+	Timestamp     time.Time         `json:"timestamp"`
+}
+
+// QUIC探测错误分类
+const (
+	QUICErrorUDPBlocked = "udp_blocked" // 未收到任何应答或收到ICMP不可达，通常是UDP被过滤
+	QUICErrorVersion    = "version"     // 版本协商失败
+	QUICErrorTLS        = "tls"         // TLS握手失败
+	QUICErrorTimeout    = "timeout"     // 收到过应答但握手或请求超时
+	QUICErrorHTTP       = "http"        // HTTP/3请求失败
+	QUICErrorOther      = "other"
+)
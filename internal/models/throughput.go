@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+// ThroughputTarget 带宽测试目标，目标需运行带宽测试接收端
+type ThroughputTarget struct {
+	IP         string            `json:"ip"`
+	Port       int               `json:"port,omitempty"`       // 接收端控制端口，默认使用配置
+	Protocol   string            `json:"protocol,omitempty"`   // tcp或udp，默认tcp
+	Duration   time.Duration     `json:"duration,omitempty"`   // 发送时长上限
+	Bytes      int64             `json:"bytes,omitempty"`      // 发送字节数上限，0表示只受时长限制
+	Rate       int64             `json:"rate,omitempty"`       // UDP发送速率，bit/s
+	PacketSize int               `json:"packetSize,omitempty"` // UDP报文大小
+	NodeName   string            `json:"nodeName"`
+	HostName   string            `json:"hostName"`
+	Tags       map[string]string `json:"tags,omitempty"` // 附加标签
+}
+
+// ThroughputResult 带宽测试结果
+type ThroughputResult struct {
+	SourceIP    string            `json:"sourceIp"`
+	TargetIP    string            `json:"targetIp"`
+	TargetNode  string            `json:"targetNode"`
+	TargetHost  string            `json:"targetHost"`
+	Protocol    string            `json:"protocol"`
+	Tags        map[string]string `json:"tags,omitempty"`
+	BytesSent   int64             `json:"bytesSent"`
+	BytesRecv   int64             `json:"bytesRecv"`
+	Duration    float64           `json:"duration"`    // 发送耗时，毫秒
+	SendBps     float64           `json:"sendBps"`     // 发送端速率，bit/s
+	RecvBps     float64           `json:"recvBps"`     // 接收端速率，bit/s
+	Retransmits int64             `json:"retransmits"` // TCP重传次数
+	PacketsSent int64             `json:"packetsSent"` // UDP
+	PacketsRecv int64             `json:"packetsRecv"` // UDP
+	Duplicates  int64             `json:"duplicates"`  // UDP
+	IPVersion   string            `json:"ipVersion"`
+	Error       string            `json:"error,omitempty"`
+	Timestamp   time.Time         `json:"timestamp"`
+}
@@ -0,0 +1,42 @@
+package models
+
+import "time"
+
+// TLSCertTarget TLS证书检查目标
+type TLSCertTarget struct {
+	Host     string            `json:"host"`           // IP或域名
+	Port     int               `json:"port,omitempty"` // 默认443
+	SNI      string            `json:"sni,omitempty"`  // 指定SNI，默认使用host
+	Timeout  time.Duration     `json:"timeout,omitempty"`
+	NodeName string            `json:"nodeName"`
+	HostName string            `json:"hostName"`
+	Tags     map[string]string `json:"tags,omitempty"` // 附加标签
+}
+
+// TLSCertResult TLS证书检查结果
+type TLSCertResult struct {
+	SourceIP      string            `json:"sourceIp"`
+	TargetIP      string            `json:"targetIp"`
+	TargetPort    int               `json:"targetPort"`
+	TargetNode    string            `json:"targetNode"`
+	TargetHost    string            `json:"targetHost"`
+	SNI           string            `json:"sni"`
+	Tags          map[string]string `json:"tags,omitempty"`
+	HandshakeTime float64           `json:"handshakeTime"` // 毫秒
+	Version       string            `json:"version"`
+	CipherSuite   string            `json:"cipherSuite"`
+	ALPN          string            `json:"alpn"`
+	Subject       string            `json:"subject"`
+	Issuer        string            `json:"issuer"`
+	IssuerCN      string            `json:"issuerCn"`
+	Serial        string            `json:"serial"`
+	NotBefore     time.Time         `json:"notBefore"`
+	NotAfter      time.Time         `json:"notAfter"`
+	DaysToExpiry  float64           `json:"daysToExpiry"`
+	ChainLength   int               `json:"chainLength"`
+	ChainValid    bool              `json:"chainValid"`
+	ChainError    string            `json:"chainError,omitempty"`
+	SANMatch      bool              `json:"sanMatch"`
+	Error         string            `json:"error,omitempty"`
+	Timestamp     time.Time         `json:"timestamp"`
+}
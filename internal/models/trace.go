@@ -0,0 +1,44 @@
+package models
+
+import "time"
+
+// TraceTarget 逐跳路径探测目标
+type TraceTarget struct {
+	IP       string            `json:"ip"`
+	Mode     string            `json:"mode,omitempty"`    // icmp/udp/tcp，默认icmp
+	Port     int               `json:"port,omitempty"`    // udp为起始端口，tcp为目标端口
+	Count    int               `json:"count,omitempty"`   // 每跳探测次数
+	MaxHops  int               `json:"maxHops,omitempty"` // 最大跳数
+	Timeout  time.Duration     `json:"timeout,omitempty"` // 每轮等待应答的时间
+	NodeName string            `json:"nodeName"`
+	HostName string            `json:"hostName"`
+	Tags     map[string]string `json:"tags,omitempty"` // 附加标签
+}
+
+// TraceHop 单跳统计，RTT单位毫秒
+type TraceHop struct {
+	TTL         int     `json:"ttl"`
+	Addr        string  `json:"addr"` // 应答最多的地址，无应答为空
+	Sent        int     `json:"sent"`
+	Recv        int     `json:"recv"`
+	MinRtt      float64 `json:"minRtt"`
+	MaxRtt      float64 `json:"maxRtt"`
+	AvgRtt      float64 `json:"avgRtt"`
+	StdDevRtt   float64 `json:"stdDevRtt"`
+	Destination bool    `json:"destination"` // 是否为目标地址
+}
+
+// TraceResult 逐跳路径探测结果
+type TraceResult struct {
+	SourceIP   string            `json:"sourceIp"`
+	TargetIP   string            `json:"targetIp"`
+	TargetNode string            `json:"targetNode"`
+	TargetHost string            `json:"targetHost"`
+	Mode       string            `json:"mode"`
+	Tags       map[string]string `json:"tags,omitempty"`
+	Hops       []TraceHop        `json:"hops"`
+	Reached    bool              `json:"reached"`
+	IPVersion  string            `json:"ipVersion"`
+	Error      string            `json:"error,omitempty"`
+	Timestamp  time.Time         `json:"timestamp"`
+}
@@ -2,6 +2,7 @@ package models
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -11,6 +12,32 @@ type TaskMessage struct {
 	TaskName   string        `json:"taskName"`
 	MetricName string        `json:"metricName"`
 	Params     []interface{} `json:"params"`
+
+	DispatchedAt  *time.Time    `json:"dispatchedAt,omitempty"`
+	Deadline      *time.Time    `json:"deadline,omitempty"`      // 超过该时间未开始执行的消息直接丢弃，为空时不过期
+	OverlapPolicy string        `json:"overlapPolicy,omitempty"` // 上一次执行未结束时的处理方式，默认skip
+	Timeout       time.Duration `json:"timeout,omitempty"`       // 单次执行的超时时间，为0时不限制
+}
+
+// 同一任务上一次执行未结束时的处理方式
+const (
+	OverlapSkip   = "skip"   // 丢弃本次
+	OverlapQueue  = "queue"  // 上一次结束后执行，只保留最新的一次
+	OverlapCancel = "cancel" // 取消上一次并立即执行
+)
+
+// Expired 消息是否已过期
+func (m TaskMessage) Expired(now time.Time) bool {
+	return m.Deadline != nil && now.After(*m.Deadline)
+}
+
+// DesiredTask 期望状态任务消息，写入节点的 <node>-desired 压缩topic，key为控制器中的任务ID，
+// 空消息表示删除。agent据此在本地按Interval调度执行，控制面不可用时继续探测
+type DesiredTask struct {
+	TaskMessage
+	Interval  time.Duration     `json:"interval"`
+	Tags      map[string]string `json:"tags,omitempty"` // 任务标签，控制器重启时据此恢复任务定义
+	UpdatedAt time.Time         `json:"updatedAt"`
 }
 
 // PingTarget 探测目标
@@ -19,6 +46,51 @@ type PingTarget struct {
 	NodeName string            `json:"nodeName"`
 	HostName string            `json:"hostName"`
 	Tags     map[string]string `json:"tags,omitempty"` // 附加标签
+
+	// 可选的探测参数，为0时使用全局ping配置
+	Count    int           `json:"count,omitempty"`
+	Interval time.Duration `json:"interval,omitempty"`
+	Timeout  time.Duration `json:"timeout,omitempty"`
+	Size     int           `json:"size,omitempty"` // ICMP载荷长度
+	TTL      int           `json:"ttl,omitempty"`
+	TOS      int           `json:"tos,omitempty"`  // IPv4 TOS / IPv6 Traffic Class
+	DSCP     int           `json:"dscp,omitempty"` // 设置后覆盖TOS
+
+	RecordRtts bool `json:"recordRtts,omitempty"` // 输出每个序号的RTT
+}
+
+// TrafficClass 返回实际使用的TOS值，DSCP优先
+func (t PingTarget) TrafficClass() int {
+	if t.DSCP > 0 {
+		return t.DSCP << 2
+	}
+	return t.TOS
+}
+
+// ProbeTags 返回被覆盖的探测参数，作为结果标签区分不同参数的序列
+func (t PingTarget) ProbeTags() map[string]string {
+	tags := make(map[string]string)
+	if t.Count > 0 {
+		tags["count"] = strconv.Itoa(t.Count)
+	}
+	if t.Interval > 0 {
+		tags["interval"] = t.Interval.String()
+	}
+	if t.Timeout > 0 {
+		tags["timeout"] = t.Timeout.String()
+	}
+	if t.Size > 0 {
+		tags["size"] = strconv.Itoa(t.Size)
+	}
+	if t.TTL > 0 {
+		tags["ttl"] = strconv.Itoa(t.TTL)
+	}
+	if t.DSCP > 0 {
+		tags["dscp"] = strconv.Itoa(t.DSCP)
+	} else if t.TOS > 0 {
+		tags["tos"] = strconv.Itoa(t.TOS)
+	}
+	return tags
 }
 
 // PingResult 探测结果
@@ -28,6 +100,7 @@ type PingResult struct {
 	TargetNode  string            `json:"targetNode"`
 	TargetHost  string            `json:"targetHost"`
 	Tags        map[string]string `json:"tags,omitempty"`
+	ProbeTags   map[string]string `json:"probeTags,omitempty"` // 覆盖的探测参数
 	PacketsSent int               `json:"packetsSent"`
 	PacketsRecv int               `json:"packetsRecv"`
 	PacketsLoss int               `json:"packetsLoss"`
@@ -35,6 +108,11 @@ type PingResult struct {
 	MaxRtt      float64           `json:"maxRtt"`
 	AvgRtt      float64           `json:"avgRtt"`
 	StdDevRtt   float64           `json:"stdDevRtt"`
+	P50Rtt      float64           `json:"p50Rtt"`
+	P90Rtt      float64           `json:"p90Rtt"`
+	P99Rtt      float64           `json:"p99Rtt"`
+	Jitter      float64           `json:"jitter"`         // RFC 3550 抖动
+	Rtts        []float64         `json:"rtts,omitempty"` // 按序号排列的RTT，丢失为-1
 	IPVersion   string            `json:"ipVersion"`
 	Error       string            `json:"error,omitempty"`
 	Timestamp   time.Time         `json:"timestamp"`
@@ -50,6 +128,10 @@ type Task struct {
 	Params     []interface{}     `json:"params"`     // 任务参数
 	Interval   time.Duration     `json:"interval"`   // 执行频率
 	Tags       map[string]string `json:"tags"`       // 任务标签，可选
+
+	OverlapPolicy string        `json:"overlapPolicy"` // skip、queue或cancel，默认skip
+	Expiry        time.Duration `json:"expiry"`        // 推送消息的有效期，默认为Interval
+	Timeout       time.Duration `json:"timeout"`       // 单次执行的超时时间，为0时不限制
 } //
 
 // internal/models/types.go
@@ -57,6 +139,14 @@ func (t *Task) GenerateKey() string {
 	return fmt.Sprintf("%s-%s", t.MetricName, strings.Join(t.NodeNames, "-"))
 }
 
+// DesiredTopicSuffix 期望状态topic的后缀，topic名为 <node>-desired
+const DesiredTopicSuffix = "-desired"
+
+// DesiredTopic 节点的期望状态topic
+func DesiredTopic(node string) string {
+	return node + DesiredTopicSuffix
+}
+
 // GetTopics 获取任务对应的所有topics
 func (t *Task) GetTopics() []string {
 	topics := make([]string, len(t.NodeNames))
@@ -66,3 +156,137 @@ func (t *Task) GetTopics() []string {
 	}
 	return topics
 }
+
+// TCPPingTarget TCP握手探测目标
+type TCPPingTarget struct {
+	IP       string            `json:"ip"`
+	Port     int               `json:"port"`
+	NodeName string            `json:"nodeName"`
+	HostName string            `json:"hostName"`
+	Tags     map[string]string `json:"tags,omitempty"` // 附加标签
+}
+
+// TCPPingResult TCP握手探测结果
+type TCPPingResult struct {
+	SourceIP      string            `json:"sourceIp"`
+	TargetIP      string            `json:"targetIp"`
+	TargetPort    int               `json:"targetPort"`
+	TargetNode    string            `json:"targetNode"`
+	TargetHost    string            `json:"targetHost"`
+	Tags          map[string]string `json:"tags,omitempty"`
+	ProbesSent    int               `json:"probesSent"`
+	ProbesSuccess int               `json:"probesSuccess"`
+	Refused       int               `json:"refused"` // 连接被拒绝(RST响应SYN)
+	Reset         int               `json:"reset"`   // 连接被重置
+	Timeout       int               `json:"timeout"` // 握手超时
+	OtherErrors   int               `json:"otherErrors"`
+	MinRtt        float64           `json:"minRtt"`
+	MaxRtt        float64           `json:"maxRtt"`
+	AvgRtt        float64           `json:"avgRtt"`
+	StdDevRtt     float64           `json:"stdDevRtt"`
+	IPVersion     string            `json:"ipVersion"`
+	Error         string            `json:"error,omitempty"`
+	Timestamp     time.Time         `json:"timestamp"`
+}
+
+// UDPPingTarget UDP反射探测目标，目标需运行UDP反射端
+type UDPPingTarget struct {
+	IP       string            `json:"ip"`
+	Port     int               `json:"port,omitempty"`  // 反射端端口，默认使用配置
+	Count    int               `json:"count,omitempty"` // 发送报文数，默认使用ping配置
+	Size     int               `json:"size,omitempty"`  // 报文大小
+	NodeName string            `json:"nodeName"`
+	HostName string            `json:"hostName"`
+	Tags     map[string]string `json:"tags,omitempty"` // 附加标签
+}
+
+// UDPPingResult UDP反射探测结果，正向为源到目标，反向为目标到源
+type UDPPingResult struct {
+	SourceIP      string            `json:"sourceIp"`
+	TargetIP      string            `json:"targetIp"`
+	TargetNode    string            `json:"targetNode"`
+	TargetHost    string            `json:"targetHost"`
+	Tags          map[string]string `json:"tags,omitempty"`
+	PacketsSent   int               `json:"packetsSent"`
+	PacketsRecv   int               `json:"packetsRecv"`
+	ReportOK      bool              `json:"reportOk"` // 是否取得反射端统计，失败时无法区分方向
+	FwdRecv       int               `json:"fwdRecv"`
+	FwdLoss       int               `json:"fwdLoss"`
+	FwdReordered  int               `json:"fwdReordered"`
+	FwdDuplicates int               `json:"fwdDuplicates"`
+	RevLoss       int               `json:"revLoss"`
+	RevReordered  int               `json:"revReordered"`
+	RevDuplicates int               `json:"revDuplicates"`
+	MinRtt        float64           `json:"minRtt"`
+	MaxRtt        float64           `json:"maxRtt"`
+	AvgRtt        float64           `json:"avgRtt"`
+	StdDevRtt     float64           `json:"stdDevRtt"`
+	IPVersion     string            `json:"ipVersion"`
+	Error         string            `json:"error,omitempty"`
+	Timestamp     time.Time         `json:"timestamp"`
+}
+
+// MTUTarget 路径MTU探测目标
+type MTUTarget struct {
+	IP       string            `json:"ip"`
+	MinMTU   int               `json:"minMtu,omitempty"` // 搜索下限，默认IPv4为576，IPv6为1280
+	MaxMTU   int               `json:"maxMtu,omitempty"` // 搜索上限，默认1500
+	NodeName string            `json:"nodeName"`
+	HostName string            `json:"hostName"`
+	Tags     map[string]string `json:"tags,omitempty"` // 附加标签
+}
+
+// MTUResult 路径MTU探测结果
+type MTUResult struct {
+	SourceIP   string            `json:"sourceIp"`
+	TargetIP   string            `json:"targetIp"`
+	TargetNode string            `json:"targetNode"`
+	TargetHost string            `json:"targetHost"`
+	Tags       map[string]string `json:"tags,omitempty"`
+	MTU        int               `json:"mtu"`    // 可通过的最大IP报文长度
+	Probes     int               `json:"probes"` // 探测次数
+	IPVersion  string            `json:"ipVersion"`
+	Error      string            `json:"error,omitempty"`
+	Timestamp  time.Time         `json:"timestamp"`
+}
+
+// STAMPTarget STAMP会话发送端探测目标
+type STAMPTarget struct {
+	IP       string            `json:"ip"`
+	Port     int               `json:"port,omitempty"`  // 反射端端口，默认使用配置
+	Count    int               `json:"count,omitempty"` // 发送报文数，默认使用ping配置
+	Size     int               `json:"size,omitempty"`  // 报文大小，不小于44
+	NodeName string            `json:"nodeName"`
+	HostName string            `json:"hostName"`
+	Tags     map[string]string `json:"tags,omitempty"` // 附加标签
+}
+
+// STAMPResult STAMP探测结果，时延单位毫秒，单向时延依赖两端时钟同步
+type STAMPResult struct {
+	SourceIP      string            `json:"sourceIp"`
+	TargetIP      string            `json:"targetIp"`
+	TargetNode    string            `json:"targetNode"`
+	TargetHost    string            `json:"targetHost"`
+	Tags          map[string]string `json:"tags,omitempty"`
+	PacketsSent   int               `json:"packetsSent"`
+	PacketsRecv   int               `json:"packetsRecv"`
+	FwdLoss       int               `json:"fwdLoss"`
+	RevLoss       int               `json:"revLoss"`
+	UnknownLoss   int               `json:"unknownLoss"` // 无法区分方向的丢包：最后一个被反射的报文之后，或反射端无状态时的全部丢包
+	FwdDelayMin   float64           `json:"fwdDelayMin"`
+	FwdDelayMax   float64           `json:"fwdDelayMax"`
+	FwdDelayAvg   float64           `json:"fwdDelayAvg"`
+	FwdJitter     float64           `json:"fwdJitter"`
+	BwdDelayMin   float64           `json:"bwdDelayMin"`
+	BwdDelayMax   float64           `json:"bwdDelayMax"`
+	BwdDelayAvg   float64           `json:"bwdDelayAvg"`
+	BwdJitter     float64           `json:"bwdJitter"`
+	MinRtt        float64           `json:"minRtt"`
+	MaxRtt        float64           `json:"maxRtt"`
+	AvgRtt        float64           `json:"avgRtt"`
+	SenderTTL     int               `json:"senderTtl"`     // 反射端收到报文时的TTL
+	ReflectorSync bool              `json:"reflectorSync"` // 反射端声明时钟已同步
+	IPVersion     string            `json:"ipVersion"`
+	Error         string            `json:"error,omitempty"`
+	Timestamp     time.Time         `json:"timestamp"`
+}
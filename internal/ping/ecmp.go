@@ -0,0 +1,293 @@
+package ping
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"net"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"net_detect/internal/models"
+	"net_detect/internal/reflector"
+	"net_detect/utils"
+)
+
+const (
+	defaultECMPFlows = 16
+	maxECMPFlows     = 256
+	flowLabelMask    = 0xfffff
+)
+
+// FlowProber 多流探测接口
+type FlowProber interface {
+	Probe(ctx context.Context, target models.ECMPTarget) models.ECMPResult
+}
+
+// DefaultFlowProber 使用多个五元组并发探测同一目标，按流统计丢包和RTT。
+// udp模式向对端UDP反射端发送报文，tcp模式以握手耗时作为RTT，收到RST同样视为路径可达
+type DefaultFlowProber struct {
+	config Config
+	port   int // UDP反射端默认端口
+}
+
+func NewFlowProber(config Config, port int) *DefaultFlowProber {
+	if port <= 0 {
+		port = reflector.DefaultPort
+	}
+	return &DefaultFlowProber{config: config, port: port}
+}
+
+// flowSpec 单条流的五元组参数
+type flowSpec struct {
+	id        int
+	srcPort   int
+	flowLabel uint32
+}
+
+func (p *DefaultFlowProber) Probe(ctx context.Context, target models.ECMPTarget) (result models.ECMPResult) {
+	result = models.ECMPResult{
+		TargetIP:   target.IP,
+		TargetPort: target.Port,
+		TargetNode: target.NodeName,
+		TargetHost: target.HostName,
+		Protocol:   target.Protocol,
+		Tags:       target.Tags,
+		IPVersion:  utils.GetIPVersion(target.IP),
+	}
+	defer func() { result.Timestamp = time.Now() }()
+
+	if result.Protocol == "" {
+		result.Protocol = "udp"
+	}
+	if result.TargetPort == 0 && result.Protocol == "udp" {
+		result.TargetPort = p.port
+	}
+	config := p.config
+	if target.Count > 0 {
+		config.Count = target.Count
+	}
+	if target.Interval > 0 {
+		config.Interval = target.Interval
+	}
+	if target.Timeout > 0 {
+		config.Timeout = target.Timeout
+	}
+	flows := target.Flows
+	if flows <= 0 {
+		flows = defaultECMPFlows
+	}
+	if flows > maxECMPFlows {
+		flows = maxECMPFlows
+	}
+
+	ip := net.ParseIP(target.IP)
+	if ip == nil {
+		result.Error = fmt.Sprintf("无效的目标地址: %s", target.IP)
+		return result
+	}
+	dst := net.JoinHostPort(target.IP, strconv.Itoa(result.TargetPort))
+	// 流标签只对IPv6有效
+	useLabel := target.FlowLabelBase > 0 && ip.To4() == nil
+
+	result.Flows = make([]models.ECMPFlowResult, flows)
+	var wg sync.WaitGroup
+	for i := 0; i < flows; i++ {
+		spec := flowSpec{id: i}
+		if target.SrcPortBase > 0 {
+			spec.srcPort = target.SrcPortBase + i
+		}
+		if useLabel {
+			spec.flowLabel = (target.FlowLabelBase + uint32(i)) & flowLabelMask
+		}
+
+		wg.Add(1)
+		go func(spec flowSpec) {
+			defer wg.Done()
+			if result.Protocol == "tcp" {
+				result.Flows[spec.id] = p.probeTCP(ctx, dst, spec, config)
+			} else {
+				result.Flows[spec.id] = p.probeUDP(ctx, dst, spec, config)
+			}
+		}(spec)
+	}
+	wg.Wait()
+
+	result.SourceIP, _ = utils.GetLocalIP(target.IP)
+	return result
+}
+
+// probeTCP 每次探测使用相同的源端口重新握手，关闭时发送RST避免TIME_WAIT占用端口，
+// ctx结束时停止探测，被中断的握手不计入发送数。未指定源端口时预先选取一个空闲端口，
+// 保证同一条流的所有握手使用相同的五元组
+func (p *DefaultFlowProber) probeTCP(ctx context.Context, dst string, spec flowSpec, config Config) models.ECMPFlowResult {
+	if spec.srcPort == 0 {
+		port, err := freeTCPPort()
+		if err != nil {
+			return models.ECMPFlowResult{FlowID: spec.id, FlowLabel: spec.flowLabel, Error: fmt.Sprintf("选取源端口失败: %v", err)}
+		}
+		spec.srcPort = port
+	}
+	flow := models.ECMPFlowResult{FlowID: spec.id, SrcPort: spec.srcPort, FlowLabel: spec.flowLabel}
+	rtts := make([]time.Duration, 0, config.Count)
+
+	for i := 0; i < config.Count && ctx.Err() == nil; i++ {
+		if i > 0 && sleepContext(ctx, config.Interval) != nil {
+			break
+		}
+
+		start := time.Now()
+		conn, err := dialFlow(ctx, "tcp", spec.srcPort, dst, spec.flowLabel, config.Timeout)
+		if ctx.Err() != nil {
+			if err == nil {
+				conn.Close()
+			}
+			break
+		}
+		rtt := time.Since(start)
+		flow.PacketsSent++
+		if err != nil {
+			switch classifyDialError(err) {
+			case "refused", "reset":
+				flow.PacketsRecv++
+				rtts = append(rtts, rtt)
+			case "timeout":
+			default:
+				flow.Error = fmt.Sprintf("建立连接失败: %v", err)
+			}
+			continue
+		}
+
+		conn.(*net.TCPConn).SetLinger(0)
+		conn.Close()
+		flow.PacketsRecv++
+		rtts = append(rtts, rtt)
+	}
+
+	setFlowRtts(&flow, rtts)
+	return flow
+}
+
+// probeUDP 通过一个固定五元组的UDP连接向反射端发送探测报文，ctx结束时停止发送
+func (p *DefaultFlowProber) probeUDP(ctx context.Context, dst string, spec flowSpec, config Config) models.ECMPFlowResult {
+	flow := models.ECMPFlowResult{FlowID: spec.id, SrcPort: spec.srcPort, FlowLabel: spec.flowLabel}
+
+	conn, err := dialFlow(ctx, "udp", spec.srcPort, dst, spec.flowLabel, config.Timeout)
+	if err != nil {
+		flow.Error = fmt.Sprintf("创建连接失败: %v", err)
+		return flow
+	}
+	defer conn.Close()
+	flow.SrcPort = conn.LocalAddr().(*net.UDPAddr).Port
+
+	sessionID := rand.Uint64()
+	var (
+		mu   sync.Mutex
+		seen = make(map[uint32]time.Duration, config.Count)
+		done = make(chan struct{})
+	)
+
+	// 接收应答
+	go func() {
+		defer close(done)
+		buf := make([]byte, 65535)
+		for {
+			n, err := conn.Read(buf)
+			if err != nil {
+				if transientReadError(err) {
+					continue
+				}
+				return
+			}
+			now := time.Now().UnixNano()
+			var pkt reflector.Packet
+			if pkt.Unmarshal(buf[:n]) != nil || pkt.Session != sessionID || pkt.Type != reflector.TypeProbeReply {
+				continue
+			}
+			mu.Lock()
+			if _, dup := seen[pkt.Seq]; !dup {
+				// 扣除反射端处理耗时
+				seen[pkt.Seq] = time.Duration(now - pkt.SendTime - (pkt.TxTime - pkt.RxTime))
+			}
+			mu.Unlock()
+		}
+	}()
+
+	for seq := 0; seq < config.Count; seq++ {
+		if seq > 0 && sleepContext(ctx, config.Interval) != nil {
+			break
+		}
+		pkt := reflector.Packet{
+			Type:     reflector.TypeProbe,
+			Session:  sessionID,
+			Seq:      uint32(seq),
+			SendTime: time.Now().UnixNano(),
+		}
+		// 发送失败同样计为丢包，对端端口不可达时ICMP错误会反映到后续的写操作上
+		flow.PacketsSent++
+		if _, err := conn.Write(pkt.Marshal(reflector.HeaderSize)); err != nil {
+			flow.Error = fmt.Sprintf("发送报文失败: %v", err)
+		}
+	}
+
+	// 等待最后的应答，ctx结束时不再等待
+	sleepContext(ctx, config.Timeout)
+	conn.Close()
+	<-done
+
+	rtts := make([]time.Duration, 0, len(seen))
+	for seq := 0; seq < flow.PacketsSent; seq++ {
+		if rtt, ok := seen[uint32(seq)]; ok {
+			rtts = append(rtts, rtt)
+		}
+	}
+	flow.PacketsRecv = len(rtts)
+	setFlowRtts(&flow, rtts)
+	return flow
+}
+
+func setFlowRtts(flow *models.ECMPFlowResult, rtts []time.Duration) {
+	stats := calcRttStats(rtts)
+	flow.MinRtt = stats.Min
+	flow.MaxRtt = stats.Max
+	flow.AvgRtt = stats.Avg
+	flow.StdDevRtt = stats.StdDev
+	flow.Jitter = stats.Jitter
+}
+
+// dialStd 使用标准库建立连接，开启SO_REUSEADDR以便重复使用同一源端口
+// freeTCPPort 由内核分配一个当前空闲的TCP端口，探测时以SO_REUSEADDR绑定
+func freeTCPPort() (int, error) {
+	l, err := net.Listen("tcp", ":0")
+	if err != nil {
+		return 0, err
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port, nil
+}
+
+func dialStd(ctx context.Context, network string, srcPort int, dst string, timeout time.Duration) (net.Conn, error) {
+	dialer := net.Dialer{
+		Timeout: timeout,
+		Control: func(network, address string, c syscall.RawConn) error {
+			var serr error
+			err := c.Control(func(fd uintptr) {
+				serr = setReuseAddr(fd)
+			})
+			if err != nil {
+				return err
+			}
+			return serr
+		},
+	}
+	if srcPort > 0 {
+		if network == "tcp" {
+			dialer.LocalAddr = &net.TCPAddr{Port: srcPort}
+		} else {
+			dialer.LocalAddr = &net.UDPAddr{Port: srcPort}
+		}
+	}
+	return dialer.DialContext(ctx, network, dst)
+}
@@ -0,0 +1,372 @@
+package ping
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"net_detect/internal/models"
+	"net_detect/utils"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+const (
+	defaultEchoSize = MinEchoSize
+	echoNonceSize   = 8
+	echoReadBuffer  = 4 << 20 // 所有目标的应答共用一个套接字，需要较大的接收缓冲区
+)
+
+var errNoEchoID = errors.New("no free icmp echo id")
+
+// ICMPEngine 共享的ICMP探测引擎，每个地址族只使用一个原始套接字，所有目标复用该套接字发包，
+// 应答按ID、序号和对端地址分发到各次探测。每次Ping分配独立的ID，负载带上引擎随机数，
+// 避免与本机其它进程的ICMP报文混淆。设置了TTL或TOS的目标需要独立的套接字选项，
+// 仍由DefaultPinger单独探测
+type ICMPEngine struct {
+	config   Config
+	fallback *DefaultPinger
+	nonce    []byte
+
+	mu sync.Mutex
+	v4 *echoSocket
+	v6 *echoSocket
+}
+
+func NewICMPEngine(config Config) *ICMPEngine {
+	nonce := make([]byte, echoNonceSize)
+	rand.Read(nonce)
+	return &ICMPEngine{
+		config:   config,
+		fallback: NewPinger(config),
+		nonce:    nonce,
+	}
+}
+
+// echoSocket 单个地址族的共享套接字
+type echoSocket struct {
+	conn  *net.IPConn
+	v6    bool
+	nonce []byte
+
+	mu       sync.Mutex
+	nextID   int
+	sessions map[int]*echoSession
+	closed   bool
+}
+
+// echoSession 一次Ping的收发状态
+type echoSession struct {
+	dst   net.IP
+	count int
+
+	mu   sync.Mutex
+	sent map[int]time.Time
+	rtts map[int]time.Duration
+	done chan struct{}
+}
+
+func (e *ICMPEngine) Ping(ctx context.Context, target models.PingTarget) (result models.PingResult) {
+	if target.TTL > 0 || target.TrafficClass() > 0 {
+		return e.fallback.Ping(ctx, target)
+	}
+
+	result = models.PingResult{
+		TargetIP:   target.IP,
+		TargetNode: target.NodeName,
+		TargetHost: target.HostName,
+		Tags:       target.Tags,
+		ProbeTags:  target.ProbeTags(),
+		IPVersion:  utils.GetIPVersion(target.IP),
+	}
+	defer func() { result.Timestamp = time.Now() }()
+
+	ip := net.ParseIP(target.IP)
+	if ip == nil {
+		result.Error = fmt.Sprintf("创建pinger失败: invalid IP address: %s", target.IP)
+		return result
+	}
+	sock, err := e.socket(ip.To4() == nil)
+	if err != nil {
+		result.Error = fmt.Sprintf("创建pinger失败: %v", err)
+		return result
+	}
+
+	config := e.fallback.targetConfig(target)
+	if config.Count > MaxEchoCount {
+		result.Error = fmt.Sprintf("count %d exceeds %d", config.Count, MaxEchoCount)
+		return result
+	}
+	id, sess, err := sock.register(ip, config.Count)
+	if err != nil {
+		result.Error = fmt.Sprintf("创建pinger失败: %v", err)
+		return result
+	}
+	defer sock.unregister(id)
+	result.SourceIP = routeSourceIP(target.IP)
+
+	// 与pro-bing保持一致，过小的负载长度直接报错而不是静默替换
+	size := target.Size
+	if size == 0 {
+		size = defaultEchoSize
+	}
+	if size < MinEchoSize {
+		result.Error = fmt.Sprintf("size %d is less than %d", size, MinEchoSize)
+		return result
+	}
+
+	// 按agent全局发包预算排队，避免大量目标同时发包
+	if err := waitPackets(ctx, config.Count); err != nil {
+		result.Error = fmt.Sprintf("执行ping失败: %v", err)
+		return result
+	}
+
+	// 与pro-bing一致，Timeout为整次探测的超时时间
+	deadline := time.NewTimer(config.Timeout)
+	defer deadline.Stop()
+	ticker := time.NewTicker(config.Interval)
+	defer ticker.Stop()
+
+send:
+	for seq := 0; seq < config.Count; seq++ {
+		if seq > 0 {
+			select {
+			case <-ticker.C:
+			case <-deadline.C:
+				break send
+			case <-ctx.Done():
+				break send
+			}
+		}
+		if err := sock.send(id, seq, sess, size); err != nil {
+			result.Error = fmt.Sprintf("执行ping失败: %v", err)
+			break
+		}
+	}
+	if result.Error == "" {
+		select {
+		case <-sess.done:
+		case <-deadline.C:
+		case <-ctx.Done():
+		}
+	}
+
+	sess.fill(&result, config.RecordRtts || target.RecordRtts)
+	return result
+}
+
+// fill 按已发送和已收到的序号填充统计，未收到应答的序号计为丢包
+func (sess *echoSession) fill(result *models.PingResult, recordRtts bool) {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	result.PacketsSent = len(sess.sent)
+	rtts := make([]time.Duration, 0, len(sess.rtts))
+	var rawRtts []float64
+	if recordRtts {
+		rawRtts = make([]float64, result.PacketsSent)
+	}
+	for seq := 0; seq < result.PacketsSent; seq++ {
+		rtt, ok := sess.rtts[seq]
+		if ok {
+			rtts = append(rtts, rtt)
+		}
+		if rawRtts != nil {
+			rawRtts[seq] = -1
+			if ok {
+				rawRtts[seq] = float64(rtt) / float64(time.Millisecond)
+			}
+		}
+	}
+	stats := calcRttStats(rtts)
+	result.PacketsRecv = len(rtts)
+	result.PacketsLoss = result.PacketsSent - result.PacketsRecv
+	result.MinRtt = stats.Min
+	result.MaxRtt = stats.Max
+	result.AvgRtt = stats.Avg
+	result.StdDevRtt = stats.StdDev
+	result.P50Rtt = stats.P50
+	result.P90Rtt = stats.P90
+	result.P99Rtt = stats.P99
+	result.Jitter = stats.Jitter
+	result.Rtts = rawRtts
+}
+
+// Close 关闭共享套接字，正在进行的探测会以丢包结束
+func (e *ICMPEngine) Close() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	var err error
+	for _, sock := range []*echoSocket{e.v4, e.v6} {
+		if sock != nil {
+			if cerr := sock.conn.Close(); cerr != nil {
+				err = cerr
+			}
+		}
+	}
+	e.v4, e.v6 = nil, nil
+	return err
+}
+
+// socket 获取地址族对应的套接字，首次使用或读取出错关闭后重新创建
+func (e *ICMPEngine) socket(v6 bool) (*echoSocket, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	slot := &e.v4
+	if v6 {
+		slot = &e.v6
+	}
+	if *slot != nil && !(*slot).isClosed() {
+		return *slot, nil
+	}
+
+	network, address := "ip4:icmp", "0.0.0.0"
+	if v6 {
+		network, address = "ip6:ipv6-icmp", "::"
+	}
+	pc, err := net.ListenPacket(network, address)
+	if err != nil {
+		return nil, err
+	}
+	conn := pc.(*net.IPConn)
+	conn.SetReadBuffer(echoReadBuffer)
+	sock := &echoSocket{
+		conn:     conn,
+		v6:       v6,
+		nonce:    e.nonce,
+		nextID:   int(e.nonce[0])<<8 | int(e.nonce[1]),
+		sessions: make(map[int]*echoSession),
+	}
+	go sock.receive()
+	*slot = sock
+	return sock, nil
+}
+
+func (s *echoSocket) isClosed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closed
+}
+
+// register 分配一个未使用的ID
+func (s *echoSocket) register(dst net.IP, count int) (int, *echoSession, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := 0; i < 1<<16; i++ {
+		id := (s.nextID + i) & 0xffff
+		if _, busy := s.sessions[id]; busy {
+			continue
+		}
+		s.nextID = id + 1
+		sess := &echoSession{
+			dst:   dst,
+			count: count,
+			sent:  make(map[int]time.Time, count),
+			rtts:  make(map[int]time.Duration, count),
+			done:  make(chan struct{}),
+		}
+		s.sessions[id] = sess
+		return id, sess, nil
+	}
+	return 0, nil, errNoEchoID
+}
+
+func (s *echoSocket) unregister(id int) {
+	s.mu.Lock()
+	delete(s.sessions, id)
+	s.mu.Unlock()
+}
+
+func (s *echoSocket) send(id, seq int, sess *echoSession, size int) error {
+	data := make([]byte, size)
+	copy(data, s.nonce)
+	msg := icmp.Message{
+		Type: ipv4.ICMPTypeEcho,
+		Body: &icmp.Echo{ID: id, Seq: seq, Data: data},
+	}
+	if s.v6 {
+		msg.Type = ipv6.ICMPTypeEchoRequest
+	}
+	b, err := msg.Marshal(nil)
+	if err != nil {
+		return err
+	}
+
+	sess.mu.Lock()
+	sess.sent[seq] = time.Now()
+	sess.mu.Unlock()
+	_, err = s.conn.WriteTo(b, &net.IPAddr{IP: sess.dst})
+	return err
+}
+
+// receive 读取应答并分发到对应的探测，套接字关闭时退出
+func (s *echoSocket) receive() {
+	defer func() {
+		s.mu.Lock()
+		s.closed = true
+		s.mu.Unlock()
+		s.conn.Close()
+	}()
+
+	proto := protocolICMP
+	if s.v6 {
+		proto = protocolIPv6ICMP
+	}
+	buf := make([]byte, 65535)
+	for {
+		n, peer, err := s.conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		s.dispatch(proto, buf[:n], peer, time.Now())
+	}
+}
+
+// dispatch 按ID、负载随机数和对端地址将应答分发到对应的探测，不匹配的报文忽略
+func (s *echoSocket) dispatch(proto int, b []byte, peer net.Addr, at time.Time) {
+	msg, err := icmp.ParseMessage(proto, b)
+	if err != nil || (msg.Type != ipv4.ICMPTypeEchoReply && msg.Type != ipv6.ICMPTypeEchoReply) {
+		return
+	}
+	echo, ok := msg.Body.(*icmp.Echo)
+	if !ok || !bytes.HasPrefix(echo.Data, s.nonce) {
+		return
+	}
+
+	s.mu.Lock()
+	sess := s.sessions[echo.ID]
+	s.mu.Unlock()
+	if sess == nil {
+		return
+	}
+	if addr, ok := peer.(*net.IPAddr); !ok || !addr.IP.Equal(sess.dst) {
+		return
+	}
+	sess.record(echo.Seq, at)
+}
+
+// record 记录应答，重复应答忽略，全部收到时通知发送方
+func (sess *echoSession) record(seq int, at time.Time) {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+
+	sent, ok := sess.sent[seq]
+	if !ok {
+		return
+	}
+	if _, dup := sess.rtts[seq]; dup {
+		return
+	}
+	sess.rtts[seq] = at.Sub(sent)
+	if len(sess.rtts) == sess.count {
+		close(sess.done)
+	}
+}
@@ -0,0 +1,206 @@
+package ping
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"net_detect/internal/models"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+)
+
+var testNonce = []byte{1, 2, 3, 4, 5, 6, 7, 8}
+
+func newTestSocket(nextID int) *echoSocket {
+	return &echoSocket{
+		nonce:    testNonce,
+		nextID:   nextID,
+		sessions: make(map[int]*echoSession),
+	}
+}
+
+func echoReply(t *testing.T, id, seq int, data []byte) []byte {
+	t.Helper()
+	b, err := (&icmp.Message{
+		Type: ipv4.ICMPTypeEchoReply,
+		Body: &icmp.Echo{ID: id, Seq: seq, Data: data},
+	}).Marshal(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestRegisterAllocatesFreeIDs(t *testing.T) {
+	sock := newTestSocket(0xfffe)
+	dst := net.ParseIP("192.0.2.1")
+
+	var ids []int
+	for i := 0; i < 3; i++ {
+		id, _, err := sock.register(dst, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, id)
+	}
+	if want := []int{0xfffe, 0xffff, 0}; ids[0] != want[0] || ids[1] != want[1] || ids[2] != want[2] {
+		t.Fatalf("ids = %v, want %v", ids, want)
+	}
+
+	// 释放后的ID在回绕后可以重新分配，占用中的ID跳过
+	sock.unregister(0xffff)
+	sock.nextID = 0xfffe
+	id, _, err := sock.register(dst, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != 0xffff {
+		t.Fatalf("id = %#x, want 0xffff", id)
+	}
+}
+
+func TestRegisterExhausted(t *testing.T) {
+	sock := newTestSocket(0)
+	for id := 0; id < 1<<16; id++ {
+		sock.sessions[id] = &echoSession{}
+	}
+	if _, _, err := sock.register(net.ParseIP("192.0.2.1"), 1); !errors.Is(err, errNoEchoID) {
+		t.Fatalf("err = %v, want %v", err, errNoEchoID)
+	}
+}
+
+func TestDispatchMatchesIDSeqAndPeer(t *testing.T) {
+	sock := newTestSocket(100)
+	dst := net.ParseIP("192.0.2.1")
+	id, sess, err := sock.register(dst, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sent := time.Now()
+	sess.sent[0] = sent
+	sess.sent[1] = sent
+
+	peer := &net.IPAddr{IP: dst}
+	data := append(append([]byte{}, testNonce...), make([]byte, 16)...)
+	at := sent.Add(5 * time.Millisecond)
+
+	cases := []struct {
+		name string
+		b    []byte
+		peer net.Addr
+	}{
+		{"other id", echoReply(t, id+1, 0, data), peer},
+		{"other nonce", echoReply(t, id, 0, make([]byte, 24)), peer},
+		{"other peer", echoReply(t, id, 0, data), &net.IPAddr{IP: net.ParseIP("192.0.2.2")}},
+		{"unsent seq", echoReply(t, id, 5, data), peer},
+	}
+	for _, c := range cases {
+		sock.dispatch(protocolICMP, c.b, c.peer, at)
+		if len(sess.rtts) != 0 {
+			t.Fatalf("%s: reply recorded", c.name)
+		}
+	}
+
+	sock.dispatch(protocolICMP, echoReply(t, id, 0, data), peer, at)
+	// 重复应答不覆盖第一次的RTT
+	sock.dispatch(protocolICMP, echoReply(t, id, 0, data), peer, at.Add(time.Second))
+	if rtt := sess.rtts[0]; rtt != 5*time.Millisecond {
+		t.Fatalf("rtt = %v, want 5ms", rtt)
+	}
+	select {
+	case <-sess.done:
+		t.Fatal("done closed before all replies received")
+	default:
+	}
+
+	sock.dispatch(protocolICMP, echoReply(t, id, 1, data), peer, at)
+	select {
+	case <-sess.done:
+	default:
+		t.Fatal("done not closed after all replies received")
+	}
+
+	// 注销后的应答忽略
+	sock.unregister(id)
+	sess.sent[2] = sent
+	sock.dispatch(protocolICMP, echoReply(t, id, 2, data), peer, at)
+	if len(sess.rtts) != 2 {
+		t.Fatalf("reply recorded after unregister")
+	}
+}
+
+func TestFillPartialStats(t *testing.T) {
+	sent := time.Now()
+	sess := &echoSession{
+		sent: map[int]time.Time{0: sent, 1: sent, 2: sent, 3: sent},
+		rtts: map[int]time.Duration{0: 2 * time.Millisecond, 2: 4 * time.Millisecond},
+	}
+	var result models.PingResult
+	sess.fill(&result, true)
+
+	if result.PacketsSent != 4 || result.PacketsRecv != 2 || result.PacketsLoss != 2 {
+		t.Fatalf("sent/recv/loss = %d/%d/%d, want 4/2/2", result.PacketsSent, result.PacketsRecv, result.PacketsLoss)
+	}
+	if result.MinRtt != 2 || result.MaxRtt != 4 || result.AvgRtt != 3 {
+		t.Fatalf("min/max/avg = %v/%v/%v, want 2/4/3", result.MinRtt, result.MaxRtt, result.AvgRtt)
+	}
+	want := []float64{2, -1, 4, -1}
+	if len(result.Rtts) != len(want) {
+		t.Fatalf("rtts = %v, want %v", result.Rtts, want)
+	}
+	for i := range want {
+		if result.Rtts[i] != want[i] {
+			t.Fatalf("rtts = %v, want %v", result.Rtts, want)
+		}
+	}
+
+	result = models.PingResult{}
+	sess.fill(&result, false)
+	if result.Rtts != nil {
+		t.Fatalf("rtts recorded without recordRtts: %v", result.Rtts)
+	}
+}
+
+func requireRawSocket(t *testing.T) {
+	t.Helper()
+	conn, err := net.ListenPacket("ip4:icmp", "0.0.0.0")
+	if err != nil {
+		t.Skipf("raw icmp socket unavailable: %v", err)
+	}
+	conn.Close()
+}
+
+func TestPingTimeout(t *testing.T) {
+	requireRawSocket(t)
+	engine := NewICMPEngine(Config{Count: 3, Interval: 10 * time.Millisecond, Timeout: 200 * time.Millisecond})
+	defer engine.Close()
+
+	// TEST-NET-2地址不会应答，超时后返回已发送的统计
+	start := time.Now()
+	result := engine.Ping(context.Background(), models.PingTarget{IP: "198.51.100.1"})
+	elapsed := time.Since(start)
+	if result.Error != "" {
+		t.Skipf("send failed: %s", result.Error)
+	}
+	if elapsed < 200*time.Millisecond || elapsed > time.Second {
+		t.Fatalf("elapsed = %v, want about 200ms", elapsed)
+	}
+	if result.PacketsSent != 3 || result.PacketsRecv != 0 || result.PacketsLoss != 3 {
+		t.Fatalf("sent/recv/loss = %d/%d/%d, want 3/0/3", result.PacketsSent, result.PacketsRecv, result.PacketsLoss)
+	}
+}
+
+func TestPingRejectsLargeCount(t *testing.T) {
+	requireRawSocket(t)
+	engine := NewICMPEngine(Config{Count: MaxEchoCount + 1, Interval: time.Millisecond, Timeout: time.Second})
+	defer engine.Close()
+
+	result := engine.Ping(context.Background(), models.PingTarget{IP: "127.0.0.1"})
+	if result.Error == "" || result.PacketsSent != 0 {
+		t.Fatalf("error = %q, sent = %d, want rejected", result.Error, result.PacketsSent)
+	}
+}
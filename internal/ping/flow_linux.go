@@ -0,0 +1,182 @@
+package ping
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// linux/in6.h 中的流标签相关常量，x/sys/unix未导出
+const (
+	ipv6FlowLabelMgr = 32
+	ipv6FlowInfoSend = 33
+	ipv6FlActionGet  = 0
+	ipv6FlFlagCreate = 1
+	ipv6FlShareAny   = 255
+
+	connectPollInterval = 100 * time.Millisecond
+)
+
+// in6FlowLabelReq 对应内核 struct in6_flowlabel_req
+type in6FlowLabelReq struct {
+	Dst     [16]byte
+	Label   uint32 // 网络字节序
+	Action  uint8
+	Share   uint8
+	Flags   uint16
+	Expires uint16
+	Linger  uint16
+	_       uint32
+}
+
+func setReuseAddr(fd uintptr) error {
+	return unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_REUSEADDR, 1)
+}
+
+// dialFlow 建立指定源端口和IPv6流标签的连接，flowLabel为0时使用标准库。
+// 标准库无法设置sin6_flowinfo，因此带流标签时直接通过系统调用创建socket并连接
+func dialFlow(ctx context.Context, network string, srcPort int, dst string, flowLabel uint32, timeout time.Duration) (net.Conn, error) {
+	if flowLabel == 0 {
+		return dialStd(ctx, network, srcPort, dst, timeout)
+	}
+
+	host, portStr, err := net.SplitHostPort(dst)
+	if err != nil {
+		return nil, err
+	}
+	port, _ := strconv.Atoi(portStr)
+	ip := net.ParseIP(host)
+	if ip == nil || ip.To4() != nil {
+		return nil, fmt.Errorf("flow label requires an IPv6 destination: %s", host)
+	}
+
+	sotype := unix.SOCK_DGRAM
+	if network == "tcp" {
+		sotype = unix.SOCK_STREAM
+	}
+	fd, err := unix.Socket(unix.AF_INET6, sotype|unix.SOCK_NONBLOCK|unix.SOCK_CLOEXEC, 0)
+	if err != nil {
+		return nil, os.NewSyscallError("socket", err)
+	}
+	ok := false
+	defer func() {
+		if !ok {
+			unix.Close(fd)
+		}
+	}()
+
+	if err := setReuseAddr(uintptr(fd)); err != nil {
+		return nil, os.NewSyscallError("setsockopt", err)
+	}
+	// 申请流标签，发送时使用connect地址中的flowinfo
+	req := in6FlowLabelReq{
+		Label:  htonl(flowLabel),
+		Action: ipv6FlActionGet,
+		Share:  ipv6FlShareAny,
+		Flags:  ipv6FlFlagCreate,
+	}
+	copy(req.Dst[:], ip.To16())
+	reqBytes := unsafe.Slice((*byte)(unsafe.Pointer(&req)), unsafe.Sizeof(req))
+	if err := unix.SetsockoptString(fd, unix.IPPROTO_IPV6, ipv6FlowLabelMgr, string(reqBytes)); err != nil {
+		return nil, os.NewSyscallError("setsockopt IPV6_FLOWLABEL_MGR", err)
+	}
+	if err := unix.SetsockoptInt(fd, unix.IPPROTO_IPV6, ipv6FlowInfoSend, 1); err != nil {
+		return nil, os.NewSyscallError("setsockopt IPV6_FLOWINFO_SEND", err)
+	}
+	if srcPort > 0 {
+		if err := unix.Bind(fd, &unix.SockaddrInet6{Port: srcPort}); err != nil {
+			return nil, os.NewSyscallError("bind", err)
+		}
+	}
+
+	sa := unix.RawSockaddrInet6{
+		Family:   unix.AF_INET6,
+		Port:     htons(uint16(port)),
+		Flowinfo: htonl(flowLabel),
+	}
+	copy(sa.Addr[:], ip.To16())
+	if zone := ipZone(host); zone != "" {
+		if ifi, err := net.InterfaceByName(zone); err == nil {
+			sa.Scope_id = uint32(ifi.Index)
+		}
+	}
+	_, _, errno := unix.Syscall(unix.SYS_CONNECT, uintptr(fd), uintptr(unsafe.Pointer(&sa)), unsafe.Sizeof(sa))
+	switch errno {
+	case 0:
+	case unix.EINPROGRESS:
+		if err := waitConnect(ctx, fd, timeout); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, os.NewSyscallError("connect", errno)
+	}
+
+	file := os.NewFile(uintptr(fd), "flow")
+	conn, err := net.FileConn(file)
+	file.Close()
+	ok = true
+	return conn, err
+}
+
+// waitConnect 等待非阻塞connect完成，每次poll不超过connectPollInterval以便及时响应ctx结束
+func waitConnect(ctx context.Context, fd int, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		remain := time.Until(deadline)
+		if remain <= 0 {
+			return os.ErrDeadlineExceeded
+		}
+		if remain > connectPollInterval {
+			remain = connectPollInterval
+		}
+		fds := []unix.PollFd{{Fd: int32(fd), Events: unix.POLLOUT}}
+		n, err := unix.Poll(fds, int(remain/time.Millisecond)+1)
+		if err == unix.EINTR {
+			continue
+		}
+		if err != nil {
+			return os.NewSyscallError("poll", err)
+		}
+		if n == 0 {
+			continue
+		}
+		soErr, err := unix.GetsockoptInt(fd, unix.SOL_SOCKET, unix.SO_ERROR)
+		if err != nil {
+			return os.NewSyscallError("getsockopt", err)
+		}
+		if soErr != 0 {
+			return os.NewSyscallError("connect", unix.Errno(soErr))
+		}
+		return nil
+	}
+}
+
+func ipZone(host string) string {
+	for i := len(host) - 1; i >= 0; i-- {
+		if host[i] == '%' {
+			return host[i+1:]
+		}
+	}
+	return ""
+}
+
+func htons(v uint16) uint16 {
+	b := (*[2]byte)(unsafe.Pointer(&v))
+	b[0], b[1] = byte(v>>8), byte(v)
+	return v
+}
+
+func htonl(v uint32) uint32 {
+	b := (*[4]byte)(unsafe.Pointer(&v))
+	b[0], b[1], b[2], b[3] = byte(v>>24), byte(v>>16), byte(v>>8), byte(v)
+	return v
+}
@@ -0,0 +1,22 @@
+//go:build !linux
+
+package ping
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+)
+
+func setReuseAddr(fd uintptr) error {
+	return nil
+}
+
+// dialFlow 非linux平台不支持指定IPv6流标签
+func dialFlow(ctx context.Context, network string, srcPort int, dst string, flowLabel uint32, timeout time.Duration) (net.Conn, error) {
+	if flowLabel != 0 {
+		return nil, fmt.Errorf("flow label is only supported on linux")
+	}
+	return dialStd(ctx, network, srcPort, dst, timeout)
+}
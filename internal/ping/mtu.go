@@ -0,0 +1,147 @@
+package ping
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"net_detect/internal/models"
+	"net_detect/utils"
+
+	probing "github.com/prometheus-community/pro-bing"
+)
+
+const (
+	ipv4ICMPOverhead = 20 + 8 // IPv4头部 + ICMP头部
+	ipv6ICMPOverhead = 40 + 8 // IPv6头部 + ICMPv6头部
+)
+
+// MTUConfig 路径MTU探测配置
+type MTUConfig struct {
+	Count    int // 每个长度发送的报文数，收到任意应答即认为可通过
+	Interval time.Duration
+	Timeout  time.Duration
+	MaxMTU   int
+}
+
+// DefaultMTUConfig 默认配置
+func DefaultMTUConfig() MTUConfig {
+	return MTUConfig{
+		Count:    3,
+		Interval: 100 * time.Millisecond,
+		Timeout:  time.Second,
+		MaxMTU:   1500,
+	}
+}
+
+// MTUProber 路径MTU探测接口
+type MTUProber interface {
+	Discover(ctx context.Context, target models.MTUTarget) models.MTUResult
+}
+
+// DefaultMTUProber 使用设置DF位的ICMP报文二分查找可通过的最大长度
+type DefaultMTUProber struct {
+	config MTUConfig
+}
+
+func NewMTUProber(config MTUConfig) *DefaultMTUProber {
+	return &DefaultMTUProber{config: config}
+}
+
+func (p *DefaultMTUProber) Discover(ctx context.Context, target models.MTUTarget) models.MTUResult {
+	result := models.MTUResult{
+		TargetIP:   target.IP,
+		TargetNode: target.NodeName,
+		TargetHost: target.HostName,
+		Tags:       target.Tags,
+		IPVersion:  utils.GetIPVersion(target.IP),
+	}
+	result.SourceIP, _ = utils.GetLocalIP(target.IP)
+
+	overhead, lo := ipv4ICMPOverhead, 576
+	if result.IPVersion == "IPv6" {
+		overhead, lo = ipv6ICMPOverhead, 1280
+	}
+	if target.MinMTU > 0 {
+		lo = target.MinMTU
+	}
+	hi := p.config.MaxMTU
+	if target.MaxMTU > 0 {
+		hi = target.MaxMTU
+	}
+	if lo-overhead < MinEchoSize || lo > hi {
+		result.Error = fmt.Sprintf("无效的MTU范围: %d-%d", lo, hi)
+		result.Timestamp = time.Now()
+		return result
+	}
+
+	probe := func(mtu int) (bool, error) {
+		result.Probes++
+		return p.probe(ctx, target.IP, mtu-overhead)
+	}
+
+	// 先确认下限可通过，再检查上限，最后在区间内二分查找
+	ok, err := probe(lo)
+	if ctx.Err() != nil {
+		result.Error = fmt.Sprintf("探测中断: %v", ctx.Err())
+		result.Timestamp = time.Now()
+		return result
+	}
+	if err != nil || !ok {
+		result.Error = fmt.Sprintf("最小长度%d无法通过", lo)
+		if err != nil {
+			result.Error = fmt.Sprintf("%s: %v", result.Error, err)
+		}
+		result.Timestamp = time.Now()
+		return result
+	}
+	if ok, _ := probe(hi); ok {
+		lo = hi
+	}
+	for lo < hi-1 && ctx.Err() == nil {
+		mid := (lo + hi) / 2
+		ok, _ := probe(mid)
+		if ctx.Err() != nil {
+			break
+		}
+		if ok {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	// 查找未完成时只能确认下限，不作为路径MTU输出，避免误报MTU变化
+	if ctx.Err() != nil {
+		result.Error = fmt.Sprintf("探测中断，已确认%d可通过: %v", lo, ctx.Err())
+		result.Timestamp = time.Now()
+		return result
+	}
+
+	result.MTU = lo
+	result.Timestamp = time.Now()
+	return result
+}
+
+// probe 发送指定载荷长度且禁止分片的ICMP报文，返回是否收到应答
+func (p *DefaultMTUProber) probe(ctx context.Context, ip string, size int) (bool, error) {
+	pinger, err := probing.NewPinger(ip)
+	if err != nil {
+		return false, err
+	}
+	pinger.Count = p.config.Count
+	pinger.Interval = p.config.Interval
+	pinger.Timeout = p.config.Timeout
+	pinger.Size = size
+	pinger.SetDoNotFragment(true)
+	pinger.SetPrivileged(true)
+
+	// 与ping共用agent全局发包预算
+	if err := waitPackets(ctx, pinger.Count); err != nil {
+		return false, err
+	}
+	// 超过本地接口MTU时发送直接失败，视为不可通过
+	if err := pinger.RunWithContext(ctx); err != nil {
+		return false, err
+	}
+	return pinger.Statistics().PacketsRecv > 0, nil
+}
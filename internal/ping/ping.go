@@ -1,6 +1,7 @@
 package ping
 
 import (
+	"context"
 	"fmt"
 	"time"
 
@@ -11,26 +12,34 @@ import (
 	probing "github.com/prometheus-community/pro-bing"
 )
 
+// MinEchoSize ICMP载荷的最小长度，pro-bing需要在载荷中写入时间戳和跟踪ID
+const MinEchoSize = 24
+
+// MaxEchoCount 单次探测的最大发包数，ICMP序号为16位，超过后序号回绕无法区分应答
+const MaxEchoCount = 1 << 16
+
 // Config ping配置
 type Config struct {
-	Count    int
-	Interval time.Duration
-	Timeout  time.Duration
+	Count      int
+	Interval   time.Duration
+	Timeout    time.Duration
+	RecordRtts bool // 结果中附带每个序号的RTT
 }
 
 // DefaultConfig 默认配置
 func DefaultConfig() Config {
 	conf := config.Get()
 	return Config{
-		Count:    conf.PingCount,
-		Interval: conf.PingInterval,
-		Timeout:  conf.PingTimeout,
+		Count:      conf.PingCount,
+		Interval:   conf.PingInterval,
+		Timeout:    conf.PingTimeout,
+		RecordRtts: conf.PingRecordRtts,
 	}
 }
 
-// Pinger ping执行器接口
+// Pinger ping执行器接口，ctx结束时停止发包并返回已有的统计
 type Pinger interface {
-	Ping(target models.PingTarget) models.PingResult
+	Ping(ctx context.Context, target models.PingTarget) models.PingResult
 }
 
 // DefaultPinger 默认的ping实现
@@ -42,7 +51,23 @@ func NewPinger(config Config) *DefaultPinger {
 	return &DefaultPinger{config: config}
 }
 
-func (p *DefaultPinger) Ping(target models.PingTarget) models.PingResult {
+// targetConfig 合并目标覆盖的探测参数
+func (p *DefaultPinger) targetConfig(target models.PingTarget) Config {
+	config := p.config
+	if target.Count > 0 {
+		config.Count = target.Count
+	}
+	if target.Interval > 0 {
+		config.Interval = target.Interval
+	}
+	if target.Timeout > 0 {
+		config.Timeout = target.Timeout
+	}
+	return config
+}
+
+func (p *DefaultPinger) Ping(ctx context.Context, target models.PingTarget) models.PingResult {
+	probeTags := target.ProbeTags()
 	pinger, err := probing.NewPinger(target.IP)
 	if err != nil {
 		return models.PingResult{
@@ -50,24 +75,57 @@ func (p *DefaultPinger) Ping(target models.PingTarget) models.PingResult {
 			TargetNode: target.NodeName,
 			TargetHost: target.HostName,
 			Tags:       target.Tags,
+			ProbeTags:  probeTags,
 			Error:      fmt.Sprintf("创建pinger失败: %v", err),
 			Timestamp:  time.Now(),
 		}
 	}
 
-	pinger.Count = p.config.Count
-	pinger.Interval = p.config.Interval
-	pinger.Timeout = p.config.Timeout
+	config := p.targetConfig(target)
+	pinger.Count = config.Count
+	pinger.Interval = config.Interval
+	pinger.Timeout = config.Timeout
+	if target.Size > 0 {
+		pinger.Size = target.Size
+	}
+	if target.TTL > 0 {
+		pinger.TTL = target.TTL
+	}
+	if tc := target.TrafficClass(); tc > 0 {
+		pinger.SetTrafficClass(uint8(tc))
+	}
 	pinger.SetPrivileged(true)
 
-	err = pinger.Run()
-	if err != nil {
+	// 按agent全局发包预算排队，避免大量目标同时发包
+	if err := waitPackets(ctx, config.Count); err != nil {
+		return models.PingResult{
+			TargetIP:   target.IP,
+			TargetNode: target.NodeName,
+			TargetHost: target.HostName,
+			Tags:       target.Tags,
+			ProbeTags:  probeTags,
+			Error:      fmt.Sprintf("执行ping失败: %v", err),
+			Timestamp:  time.Now(),
+			IPVersion:  utils.GetIPVersion(target.IP),
+		}
+	}
+
+	// 按序号记录RTT，用于计算百分位和抖动
+	seqRtts := make(map[int]time.Duration, config.Count)
+	pinger.OnRecv = func(pkt *probing.Packet) {
+		seqRtts[pkt.Seq] = pkt.Rtt
+	}
+
+	// ctx结束时pro-bing返回ctx的错误，此时仍返回已有的统计
+	err = pinger.RunWithContext(ctx)
+	if err != nil && ctx.Err() == nil {
 		return models.PingResult{
 			SourceIP:   pinger.Source,
 			TargetIP:   target.IP,
 			TargetNode: target.NodeName,
 			TargetHost: target.HostName,
 			Tags:       target.Tags,
+			ProbeTags:  probeTags,
 			Error:      fmt.Sprintf("执行ping失败: %v", err),
 			Timestamp:  time.Now(),
 			IPVersion:  utils.GetIPVersion(target.IP),
@@ -78,12 +136,33 @@ func (p *DefaultPinger) Ping(target models.PingTarget) models.PingResult {
 		source_ip, _ = utils.GetLocalIP(target.IP)
 	}
 	stats := pinger.Statistics()
+
+	rtts := make([]time.Duration, 0, len(seqRtts))
+	var rawRtts []float64
+	if config.RecordRtts || target.RecordRtts {
+		rawRtts = make([]float64, stats.PacketsSent)
+	}
+	for seq := 0; seq < stats.PacketsSent; seq++ {
+		rtt, ok := seqRtts[seq]
+		if ok {
+			rtts = append(rtts, rtt)
+		}
+		if rawRtts != nil {
+			rawRtts[seq] = -1
+			if ok {
+				rawRtts[seq] = float64(rtt) / float64(time.Millisecond)
+			}
+		}
+	}
+	extra := calcRttStats(rtts)
+
 	return models.PingResult{
 		SourceIP:    source_ip,
 		TargetIP:    target.IP,
 		TargetNode:  target.NodeName,
 		TargetHost:  target.HostName,
 		Tags:        target.Tags,
+		ProbeTags:   probeTags,
 		PacketsSent: stats.PacketsSent,
 		PacketsRecv: stats.PacketsRecv,
 		PacketsLoss: stats.PacketsSent - stats.PacketsRecv,
@@ -91,6 +170,11 @@ func (p *DefaultPinger) Ping(target models.PingTarget) models.PingResult {
 		MaxRtt:      float64(stats.MaxRtt) / float64(time.Millisecond),
 		AvgRtt:      float64(stats.AvgRtt) / float64(time.Millisecond),
 		StdDevRtt:   float64(stats.StdDevRtt) / float64(time.Millisecond),
+		P50Rtt:      extra.P50,
+		P90Rtt:      extra.P90,
+		P99Rtt:      extra.P99,
+		Jitter:      extra.Jitter,
+		Rtts:        rawRtts,
 		IPVersion:   utils.GetIPVersion(target.IP),
 		Timestamp:   time.Now(),
 	}
@@ -0,0 +1,91 @@
+package ping
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// RateLimiter 令牌桶限速，单次可以申请超过桶容量的令牌，此时按欠额等待
+type RateLimiter struct {
+	mu     sync.Mutex
+	rate   float64 // 每秒令牌数
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// NewRateLimiter 创建限速器，rate<=0时返回nil表示不限速
+func NewRateLimiter(rate int) *RateLimiter {
+	if rate <= 0 {
+		return nil
+	}
+	return &RateLimiter{
+		rate:   float64(rate),
+		burst:  float64(rate),
+		tokens: float64(rate),
+		last:   time.Now(),
+	}
+}
+
+// reserve 预留n个令牌并返回需要等待的时间
+func (l *RateLimiter) reserve(n int) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+	l.last = now
+
+	l.tokens -= float64(n)
+	if l.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-l.tokens / l.rate * float64(time.Second))
+}
+
+// Wait 等待直到可以发送n个报文，nil表示不限速。ctx结束时返回错误，已预留的令牌不退回
+func (l *RateLimiter) Wait(ctx context.Context, n int) error {
+	if l == nil || n <= 0 {
+		return nil
+	}
+	if d := l.reserve(n); d > 0 {
+		return sleepContext(ctx, d)
+	}
+	return nil
+}
+
+var (
+	packetLimiterMu sync.RWMutex
+	packetLimiter   *RateLimiter
+)
+
+// SetPacketRate 设置agent全局的ICMP探测发包速率，所有任务共享，pps<=0表示不限速
+func SetPacketRate(pps int) {
+	packetLimiterMu.Lock()
+	packetLimiter = NewRateLimiter(pps)
+	packetLimiterMu.Unlock()
+}
+
+// waitPackets 按全局发包速率等待
+func waitPackets(ctx context.Context, n int) error {
+	packetLimiterMu.RLock()
+	l := packetLimiter
+	packetLimiterMu.RUnlock()
+	return l.Wait(ctx, n)
+}
+
+// sleepContext 等待d或ctx结束，ctx结束时返回ctx的错误
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
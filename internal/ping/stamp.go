@@ -0,0 +1,169 @@
+package ping
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"net_detect/internal/clock"
+	"net_detect/internal/models"
+	"net_detect/internal/stamp"
+	"net_detect/utils"
+)
+
+// STAMPSender STAMP会话发送端接口
+type STAMPSender interface {
+	Send(ctx context.Context, target models.STAMPTarget) models.STAMPResult
+}
+
+// DefaultSTAMPSender 按RFC 8762向反射端发送测试报文，分别计算正向和反向时延，
+// 并根据有状态反射端的序号区分正向和反向丢包
+type DefaultSTAMPSender struct {
+	config Config
+	port   int
+}
+
+func NewSTAMPSender(config Config, port int) *DefaultSTAMPSender {
+	if port <= 0 {
+		port = stamp.DefaultPort
+	}
+	return &DefaultSTAMPSender{config: config, port: port}
+}
+
+func (p *DefaultSTAMPSender) Send(ctx context.Context, target models.STAMPTarget) (result models.STAMPResult) {
+	result = models.STAMPResult{
+		TargetIP:   target.IP,
+		TargetNode: target.NodeName,
+		TargetHost: target.HostName,
+		Tags:       target.Tags,
+		IPVersion:  utils.GetIPVersion(target.IP),
+	}
+	defer func() { result.Timestamp = time.Now() }()
+
+	port := target.Port
+	if port == 0 {
+		port = p.port
+	}
+	count := p.config.Count
+	if target.Count > 0 {
+		count = target.Count
+	}
+
+	conn, err := net.Dial("udp", net.JoinHostPort(target.IP, strconv.Itoa(port)))
+	if err != nil {
+		result.Error = fmt.Sprintf("创建连接失败: %v", err)
+		return result
+	}
+	defer conn.Close()
+	result.SourceIP = conn.LocalAddr().(*net.UDPAddr).IP.String()
+
+	type sample struct {
+		fwd, bwd, rtt time.Duration
+	}
+	var (
+		samples      = make(map[uint32]sample, count)
+		maxReflected = -1 // 收到的最大反射端序号
+		maxSender    = -1 // 该应答对应的发送序号
+		stateful     bool // 有应答的反射端序号与发送序号不同
+		done         = make(chan struct{})
+	)
+
+	// 接收反射报文
+	go func() {
+		defer close(done)
+		buf := make([]byte, 65535)
+		for {
+			n, err := conn.Read(buf)
+			if err != nil {
+				if transientReadError(err) {
+					continue
+				}
+				return
+			}
+			t4 := time.Now()
+			var reply stamp.ReflectorPacket
+			if reply.Unmarshal(buf[:n]) != nil {
+				continue
+			}
+			if _, dup := samples[reply.SenderSeq]; dup || int(reply.SenderSeq) >= count {
+				continue
+			}
+
+			t1, t2, t3 := reply.SenderTimestamp, reply.ReceiveTimestamp, reply.Timestamp
+			samples[reply.SenderSeq] = sample{
+				fwd: t2.Sub(t1),
+				bwd: t4.Sub(t3),
+				rtt: t4.Sub(t1) - t3.Sub(t2),
+			}
+			if reply.Seq != reply.SenderSeq {
+				stateful = true
+			}
+			if int(reply.Seq) > maxReflected {
+				maxReflected = int(reply.Seq)
+				maxSender = int(reply.SenderSeq)
+			}
+			result.SenderTTL = int(reply.SenderTTL)
+			result.ReflectorSync = reply.ErrorEstimate&(1<<15) != 0
+		}
+	}()
+
+	for seq := 0; seq < count; seq++ {
+		if seq > 0 && sleepContext(ctx, p.config.Interval) != nil {
+			break
+		}
+		pkt := stamp.SenderPacket{
+			Seq:           uint32(seq),
+			Timestamp:     time.Now(),
+			ErrorEstimate: stamp.ErrorEstimate(clock.Synced()),
+		}
+		if _, err := conn.Write(pkt.Marshal(target.Size)); err != nil {
+			result.Error = fmt.Sprintf("发送报文失败: %v", err)
+			continue
+		}
+		result.PacketsSent++
+	}
+
+	// 等待在途报文后结束接收，ctx结束时不再等待
+	sleepContext(ctx, p.config.Timeout)
+	conn.Close()
+	<-done
+
+	// 有状态反射端的序号从0开始按收到的报文递增。已知反射端收到发送序号maxSender时
+	// 共收到maxReflected+1个报文，据此只对该序号及之前的报文区分正反向丢包；
+	// 之后的报文丢失时无法判断丢在哪个方向，计为UnknownLoss
+	result.PacketsRecv = len(samples)
+	recvUpTo := 0
+	for seq := range samples {
+		if int(seq) <= maxSender {
+			recvUpTo++
+		}
+	}
+	sentUpTo, reflected := maxSender+1, maxReflected+1
+	result.FwdLoss = sentUpTo - reflected
+	result.RevLoss = reflected - recvUpTo
+	result.UnknownLoss = (result.PacketsSent - sentUpTo) - (result.PacketsRecv - recvUpTo)
+	// 无状态反射端(RFC 8762)直接回填发送序号，所有应答的两个序号都相同时无法判断反射端是否
+	// 有状态，丢包全部计为UnknownLoss
+	if !stateful {
+		result.FwdLoss, result.RevLoss = 0, 0
+		result.UnknownLoss = result.PacketsSent - result.PacketsRecv
+	}
+
+	fwd := make([]time.Duration, 0, len(samples))
+	bwd := make([]time.Duration, 0, len(samples))
+	rtts := make([]time.Duration, 0, len(samples))
+	for seq := 0; seq < count; seq++ {
+		if s, ok := samples[uint32(seq)]; ok {
+			fwd = append(fwd, s.fwd)
+			bwd = append(bwd, s.bwd)
+			rtts = append(rtts, s.rtt)
+		}
+	}
+	fwdStats, bwdStats, rttStats := calcRttStats(fwd), calcRttStats(bwd), calcRttStats(rtts)
+	result.FwdDelayMin, result.FwdDelayMax, result.FwdDelayAvg, result.FwdJitter = fwdStats.Min, fwdStats.Max, fwdStats.Avg, fwdStats.Jitter
+	result.BwdDelayMin, result.BwdDelayMax, result.BwdDelayAvg, result.BwdJitter = bwdStats.Min, bwdStats.Max, bwdStats.Avg, bwdStats.Jitter
+	result.MinRtt, result.MaxRtt, result.AvgRtt = rttStats.Min, rttStats.Max, rttStats.Avg
+	return result
+}
@@ -0,0 +1,68 @@
+package ping
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// rttStats RTT统计结果，单位毫秒
+type rttStats struct {
+	Min    float64
+	Max    float64
+	Avg    float64
+	StdDev float64
+	P50    float64
+	P90    float64
+	P99    float64
+	Jitter float64 // RFC 3550 到达间隔抖动
+}
+
+// calcRttStats 根据按序号排列的RTT样本计算统计值
+func calcRttStats(rtts []time.Duration) rttStats {
+	var s rttStats
+	if len(rtts) == 0 {
+		return s
+	}
+
+	var sum float64
+	s.Min = math.MaxFloat64
+	for _, rtt := range rtts {
+		ms := float64(rtt) / float64(time.Millisecond)
+		sum += ms
+		s.Min = math.Min(s.Min, ms)
+		s.Max = math.Max(s.Max, ms)
+	}
+	s.Avg = sum / float64(len(rtts))
+
+	var variance float64
+	for _, rtt := range rtts {
+		d := float64(rtt)/float64(time.Millisecond) - s.Avg
+		variance += d * d
+	}
+	s.StdDev = math.Sqrt(variance / float64(len(rtts)))
+
+	// RFC 3550: J = J + (|D(i-1,i)| - J) / 16，D为相邻报文传输时间之差，
+	// 往返探测中以RTT之差代替
+	for i := 1; i < len(rtts); i++ {
+		d := math.Abs(float64(rtts[i]-rtts[i-1]) / float64(time.Millisecond))
+		s.Jitter += (d - s.Jitter) / 16
+	}
+
+	sorted := make([]time.Duration, len(rtts))
+	copy(sorted, rtts)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	s.P50 = percentile(sorted, 50)
+	s.P90 = percentile(sorted, 90)
+	s.P99 = percentile(sorted, 99)
+	return s
+}
+
+// percentile 最近秩法计算百分位数，sorted需已升序排列
+func percentile(sorted []time.Duration, p float64) float64 {
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return float64(sorted[rank-1]) / float64(time.Millisecond)
+}
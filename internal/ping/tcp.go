@@ -0,0 +1,107 @@
+package ping
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"syscall"
+	"time"
+
+	"net_detect/internal/models"
+	"net_detect/utils"
+)
+
+// TCPPinger TCP握手探测接口
+type TCPPinger interface {
+	Ping(ctx context.Context, target models.TCPPingTarget) models.TCPPingResult
+}
+
+// DefaultTCPPinger 默认的TCP握手探测实现，复用ping的Count/Interval/Timeout配置，
+// 其中Timeout作为单次握手的超时时间
+type DefaultTCPPinger struct {
+	config Config
+}
+
+func NewTCPPinger(config Config) *DefaultTCPPinger {
+	return &DefaultTCPPinger{config: config}
+}
+
+func (p *DefaultTCPPinger) Ping(ctx context.Context, target models.TCPPingTarget) models.TCPPingResult {
+	result := models.TCPPingResult{
+		TargetIP:   target.IP,
+		TargetPort: target.Port,
+		TargetNode: target.NodeName,
+		TargetHost: target.HostName,
+		Tags:       target.Tags,
+		IPVersion:  utils.GetIPVersion(target.IP),
+	}
+
+	addr := net.JoinHostPort(target.IP, strconv.Itoa(target.Port))
+	dialer := net.Dialer{Timeout: p.config.Timeout}
+	rtts := make([]time.Duration, 0, p.config.Count)
+
+	for i := 0; i < p.config.Count && ctx.Err() == nil; i++ {
+		if i > 0 && sleepContext(ctx, p.config.Interval) != nil {
+			break
+		}
+
+		start := time.Now()
+		conn, err := dialer.DialContext(ctx, "tcp", addr)
+		if ctx.Err() != nil {
+			break
+		}
+		rtt := time.Since(start)
+		result.ProbesSent++
+		if err != nil {
+			switch classifyDialError(err) {
+			case "refused":
+				result.Refused++
+			case "reset":
+				result.Reset++
+			case "timeout":
+				result.Timeout++
+			default:
+				result.OtherErrors++
+				result.Error = fmt.Sprintf("建立连接失败: %v", err)
+			}
+			continue
+		}
+
+		if result.SourceIP == "" {
+			if local, ok := conn.LocalAddr().(*net.TCPAddr); ok {
+				result.SourceIP = local.IP.String()
+			}
+		}
+		conn.Close()
+		result.ProbesSuccess++
+		rtts = append(rtts, rtt)
+	}
+
+	if result.SourceIP == "" {
+		result.SourceIP, _ = utils.GetLocalIP(target.IP)
+	}
+	stats := calcRttStats(rtts)
+	result.MinRtt = stats.Min
+	result.MaxRtt = stats.Max
+	result.AvgRtt = stats.Avg
+	result.StdDevRtt = stats.StdDev
+	result.Timestamp = time.Now()
+	return result
+}
+
+// classifyDialError 将建连错误归类为 refused/reset/timeout/other
+func classifyDialError(err error) string {
+	var netErr net.Error
+	switch {
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return "refused"
+	case errors.Is(err, syscall.ECONNRESET):
+		return "reset"
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return "timeout"
+	default:
+		return "other"
+	}
+}
@@ -0,0 +1,460 @@
+package ping
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math/rand"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"net_detect/internal/models"
+	"net_detect/utils"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+const (
+	TraceModeICMP = "icmp"
+	TraceModeUDP  = "udp"
+	TraceModeTCP  = "tcp"
+
+	protocolICMP     = 1
+	protocolTCP      = 6
+	protocolUDP      = 17
+	protocolIPv6ICMP = 58
+)
+
+// TraceConfig 逐跳探测默认配置
+type TraceConfig struct {
+	Count   int
+	MaxHops int
+	Timeout time.Duration
+	UDPPort int // udp模式起始目的端口
+	TCPPort int // tcp模式目的端口
+}
+
+// DefaultTraceConfig 默认配置，行为与mtr一致
+func DefaultTraceConfig() TraceConfig {
+	return TraceConfig{
+		Count:   10,
+		MaxHops: 30,
+		Timeout: time.Second,
+		UDPPort: 33434,
+		TCPPort: 80,
+	}
+}
+
+// Tracer 逐跳路径探测接口
+type Tracer interface {
+	Trace(ctx context.Context, target models.TraceTarget) models.TraceResult
+}
+
+// DefaultTracer 默认的逐跳探测实现。每轮对所有TTL各发送一个探测包，
+// 通过原始ICMP套接字收集超时/不可达应答，共探测Count轮
+type DefaultTracer struct {
+	config TraceConfig
+}
+
+func NewTracer(config TraceConfig) *DefaultTracer {
+	return &DefaultTracer{config: config}
+}
+
+// traceReply 一次应答，key为探测标识
+type traceReply struct {
+	key         int
+	from        string
+	at          time.Time
+	destination bool
+}
+
+// traceRun 单次逐跳探测的状态
+type traceRun struct {
+	target  net.IP
+	v6      bool
+	mode    string
+	port    int
+	id      int
+	conn    *icmp.PacketConn
+	replies chan traceReply
+
+	mu    sync.Mutex
+	ports map[int]int // 本端端口 -> 探测标识，用于udp/tcp模式匹配
+}
+
+func (p *DefaultTracer) Trace(ctx context.Context, target models.TraceTarget) models.TraceResult {
+	result := models.TraceResult{
+		TargetIP:   target.IP,
+		TargetNode: target.NodeName,
+		TargetHost: target.HostName,
+		Mode:       target.Mode,
+		Tags:       target.Tags,
+		IPVersion:  utils.GetIPVersion(target.IP),
+	}
+	if result.Mode == "" {
+		result.Mode = TraceModeICMP
+	}
+
+	cfg := p.config
+	if target.Count > 0 {
+		cfg.Count = target.Count
+	}
+	if target.MaxHops > 0 {
+		cfg.MaxHops = target.MaxHops
+	}
+	if target.Timeout > 0 {
+		cfg.Timeout = target.Timeout
+	}
+
+	run, err := newTraceRun(target, result.Mode, cfg)
+	if err != nil {
+		result.Error = fmt.Sprintf("初始化探测失败: %v", err)
+		result.Timestamp = time.Now()
+		return result
+	}
+	defer run.conn.Close()
+	go run.receive()
+
+	result.SourceIP = routeSourceIP(target.IP)
+	result.Hops, result.Reached = run.rounds(ctx, cfg)
+	result.Timestamp = time.Now()
+	return result
+}
+
+func newTraceRun(target models.TraceTarget, mode string, cfg TraceConfig) (*traceRun, error) {
+	ip := net.ParseIP(target.IP)
+	if ip == nil {
+		return nil, fmt.Errorf("invalid IP address: %s", target.IP)
+	}
+	run := &traceRun{
+		target:  ip,
+		v6:      ip.To4() == nil,
+		mode:    mode,
+		port:    target.Port,
+		id:      rand.Intn(1 << 16),
+		replies: make(chan traceReply, 256),
+		ports:   make(map[int]int),
+	}
+	switch mode {
+	case TraceModeUDP:
+		if run.port == 0 {
+			run.port = cfg.UDPPort
+		}
+	case TraceModeTCP:
+		if run.port == 0 {
+			run.port = cfg.TCPPort
+		}
+	case TraceModeICMP:
+	default:
+		return nil, fmt.Errorf("unsupported trace mode: %s", mode)
+	}
+
+	var err error
+	if run.v6 {
+		run.conn, err = icmp.ListenPacket("ip6:ipv6-icmp", "::")
+	} else {
+		run.conn, err = icmp.ListenPacket("ip4:icmp", "0.0.0.0")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return run, nil
+}
+
+// probeKey 探测标识，由轮次和TTL组成
+func probeKey(round, ttl int) int {
+	return (round&0xff)<<8 | ttl&0xff
+}
+
+// rounds 执行所有轮次的探测并汇总每跳统计，ctx结束时停止发送，
+// 中断轮次中未收到应答的探测不计入发送数
+func (r *traceRun) rounds(ctx context.Context, cfg TraceConfig) ([]models.TraceHop, bool) {
+	type hopState struct {
+		sent  int
+		rtts  []time.Duration
+		addrs map[string]int
+	}
+	hops := make([]hopState, cfg.MaxHops+1)
+	destTTL := 0
+
+	for round := 0; round < cfg.Count && ctx.Err() == nil; round++ {
+		maxTTL := cfg.MaxHops
+		if destTTL > 0 {
+			maxTTL = destTTL
+		}
+
+		// 每轮按发送的探测数计入agent全局发包预算
+		if waitPackets(ctx, maxTTL) != nil {
+			break
+		}
+
+		sentAt := make(map[int]time.Time, maxTTL)
+		var udpConn *net.UDPConn
+		var wg sync.WaitGroup
+		for ttl := 1; ttl <= maxTTL && ctx.Err() == nil; ttl++ {
+			key := probeKey(round, ttl)
+			sentAt[key] = time.Now()
+			hops[ttl].sent++
+			switch r.mode {
+			case TraceModeICMP:
+				r.sendICMP(ttl, key)
+			case TraceModeUDP:
+				udpConn = r.sendUDP(udpConn, ttl, key)
+			case TraceModeTCP:
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					r.sendTCP(ctx, ttl, key, cfg.Timeout)
+				}()
+			}
+		}
+
+		// 等待本轮应答，全部收到或超时后进入下一轮
+		deadline := time.NewTimer(cfg.Timeout)
+	collect:
+		for len(sentAt) > 0 {
+			select {
+			case reply := <-r.replies:
+				start, ok := sentAt[reply.key]
+				if !ok {
+					continue
+				}
+				delete(sentAt, reply.key)
+				ttl := reply.key & 0xff
+				hops[ttl].rtts = append(hops[ttl].rtts, reply.at.Sub(start))
+				if hops[ttl].addrs == nil {
+					hops[ttl].addrs = make(map[string]int)
+				}
+				hops[ttl].addrs[reply.from]++
+				if reply.destination && (destTTL == 0 || ttl < destTTL) {
+					destTTL = ttl
+				}
+			case <-deadline.C:
+				break collect
+			case <-ctx.Done():
+				for key := range sentAt {
+					hops[key&0xff].sent--
+				}
+				break collect
+			}
+		}
+		deadline.Stop()
+		if udpConn != nil {
+			udpConn.Close()
+		}
+		wg.Wait()
+	}
+
+	last := cfg.MaxHops
+	if destTTL > 0 {
+		last = destTTL
+	}
+	result := make([]models.TraceHop, 0, last)
+	for ttl := 1; ttl <= last; ttl++ {
+		h := hops[ttl]
+		// 被中断时未发出的跳不输出
+		if h.sent == 0 {
+			continue
+		}
+		hop := models.TraceHop{
+			TTL:         ttl,
+			Sent:        h.sent,
+			Recv:        len(h.rtts),
+			Destination: ttl == destTTL,
+		}
+		best := 0
+		for addr, n := range h.addrs {
+			if n > best {
+				hop.Addr, best = addr, n
+			}
+		}
+		stats := calcRttStats(h.rtts)
+		hop.MinRtt = stats.Min
+		hop.MaxRtt = stats.Max
+		hop.AvgRtt = stats.Avg
+		hop.StdDevRtt = stats.StdDev
+		result = append(result, hop)
+	}
+	return result, destTTL > 0
+}
+
+func (r *traceRun) sendICMP(ttl, key int) {
+	msg := icmp.Message{
+		Type: ipv4.ICMPTypeEcho,
+		Body: &icmp.Echo{ID: r.id, Seq: key, Data: []byte("net_detect-trace")},
+	}
+	var err error
+	if r.v6 {
+		msg.Type = ipv6.ICMPTypeEchoRequest
+		err = r.conn.IPv6PacketConn().SetHopLimit(ttl)
+	} else {
+		err = r.conn.IPv4PacketConn().SetTTL(ttl)
+	}
+	if err != nil {
+		return
+	}
+	b, err := msg.Marshal(nil)
+	if err != nil {
+		return
+	}
+	r.conn.WriteTo(b, &net.IPAddr{IP: r.target})
+}
+
+// sendUDP 发送UDP探测，目的端口为起始端口+TTL，每轮使用新的源端口
+func (r *traceRun) sendUDP(conn *net.UDPConn, ttl, key int) *net.UDPConn {
+	if conn == nil {
+		network := "udp4"
+		if r.v6 {
+			network = "udp6"
+		}
+		var err error
+		conn, err = net.ListenUDP(network, nil)
+		if err != nil {
+			return nil
+		}
+		r.mu.Lock()
+		r.ports[conn.LocalAddr().(*net.UDPAddr).Port] = key &^ 0xff
+		r.mu.Unlock()
+	}
+
+	var err error
+	if r.v6 {
+		err = ipv6.NewConn(conn).SetHopLimit(ttl)
+	} else {
+		err = ipv4.NewConn(conn).SetTTL(ttl)
+	}
+	if err != nil {
+		return conn
+	}
+	conn.WriteToUDP([]byte("net_detect-trace"), &net.UDPAddr{IP: r.target, Port: r.port + ttl})
+	return conn
+}
+
+// sendTCP 发起一次限定TTL的TCP连接，连接成功或被拒绝均表示到达目标
+func (r *traceRun) sendTCP(ctx context.Context, ttl, key int, timeout time.Duration) {
+	dialer := net.Dialer{
+		Timeout: timeout,
+		Control: tcpTraceControl(ttl, func(port int) {
+			r.mu.Lock()
+			r.ports[port] = key
+			r.mu.Unlock()
+		}),
+	}
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(r.target.String(), strconv.Itoa(r.port)))
+	if err == nil {
+		conn.Close()
+	}
+	if err == nil || classifyDialError(err) == "refused" {
+		r.deliver(traceReply{key: key, from: r.target.String(), at: time.Now(), destination: true})
+	}
+}
+
+// receive 读取ICMP应答并匹配到探测标识
+func (r *traceRun) receive() {
+	proto := protocolICMP
+	if r.v6 {
+		proto = protocolIPv6ICMP
+	}
+	buf := make([]byte, 1500)
+	for {
+		n, peer, err := r.conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		at := time.Now()
+		msg, err := icmp.ParseMessage(proto, buf[:n])
+		if err != nil {
+			continue
+		}
+
+		from := peer.String()
+		if addr, ok := peer.(*net.IPAddr); ok {
+			from = addr.IP.String()
+		}
+		switch body := msg.Body.(type) {
+		case *icmp.Echo:
+			if (msg.Type == ipv4.ICMPTypeEchoReply || msg.Type == ipv6.ICMPTypeEchoReply) &&
+				r.mode == TraceModeICMP && body.ID == r.id {
+				r.deliver(traceReply{key: body.Seq, from: from, at: at, destination: true})
+			}
+		case *icmp.TimeExceeded:
+			if key, ok := r.matchInner(body.Data); ok {
+				r.deliver(traceReply{key: key, from: from, at: at})
+			}
+		case *icmp.DstUnreach:
+			if key, ok := r.matchInner(body.Data); ok {
+				r.deliver(traceReply{key: key, from: from, at: at, destination: from == r.target.String()})
+			}
+		}
+	}
+}
+
+func (r *traceRun) deliver(reply traceReply) {
+	select {
+	case r.replies <- reply:
+	default:
+	}
+}
+
+// matchInner 解析ICMP差错报文携带的原始报文，匹配本次探测
+func (r *traceRun) matchInner(data []byte) (int, bool) {
+	var proto int
+	var dst net.IP
+	var l4 []byte
+	if r.v6 {
+		if len(data) < 48 {
+			return 0, false
+		}
+		proto, dst, l4 = int(data[6]), net.IP(data[24:40]), data[40:]
+	} else {
+		if len(data) < 20 {
+			return 0, false
+		}
+		ihl := int(data[0]&0x0f) * 4
+		if len(data) < ihl+8 {
+			return 0, false
+		}
+		proto, dst, l4 = int(data[9]), net.IP(data[16:20]), data[ihl:]
+	}
+	if !dst.Equal(r.target) {
+		return 0, false
+	}
+
+	switch {
+	case r.mode == TraceModeICMP && (proto == protocolICMP || proto == protocolIPv6ICMP):
+		if int(binary.BigEndian.Uint16(l4[4:6])) != r.id {
+			return 0, false
+		}
+		return int(binary.BigEndian.Uint16(l4[6:8])), true
+	case r.mode == TraceModeUDP && proto == protocolUDP:
+		r.mu.Lock()
+		round, ok := r.ports[int(binary.BigEndian.Uint16(l4[0:2]))]
+		r.mu.Unlock()
+		ttl := int(binary.BigEndian.Uint16(l4[2:4])) - r.port
+		if !ok || ttl <= 0 || ttl > 0xff {
+			return 0, false
+		}
+		return round | ttl, true
+	case r.mode == TraceModeTCP && proto == protocolTCP:
+		r.mu.Lock()
+		key, ok := r.ports[int(binary.BigEndian.Uint16(l4[0:2]))]
+		r.mu.Unlock()
+		return key, ok
+	}
+	return 0, false
+}
+
+// routeSourceIP 获取访问目标时使用的本端地址
+func routeSourceIP(targetIP string) string {
+	conn, err := net.Dial("udp", net.JoinHostPort(targetIP, "9"))
+	if err != nil {
+		ip, _ := utils.GetLocalIP(targetIP)
+		return ip
+	}
+	defer conn.Close()
+	return conn.LocalAddr().(*net.UDPAddr).IP.String()
+}
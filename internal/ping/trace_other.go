@@ -0,0 +1,14 @@
+//go:build !unix
+
+package ping
+
+import (
+	"errors"
+	"syscall"
+)
+
+func tcpTraceControl(ttl int, onBind func(port int)) func(network, address string, c syscall.RawConn) error {
+	return func(network, address string, c syscall.RawConn) error {
+		return errors.New("tcp trace mode is not supported on this platform")
+	}
+}
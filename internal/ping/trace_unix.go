@@ -0,0 +1,47 @@
+//go:build unix
+
+package ping
+
+import (
+	"syscall"
+)
+
+// tcpTraceControl 连接前设置TTL并绑定本端端口，通过onBind返回端口用于匹配ICMP应答
+func tcpTraceControl(ttl int, onBind func(port int)) func(network, address string, c syscall.RawConn) error {
+	return func(network, address string, c syscall.RawConn) error {
+		var opErr error
+		err := c.Control(func(fd uintptr) {
+			s := int(fd)
+			if network == "tcp6" {
+				opErr = syscall.SetsockoptInt(s, syscall.IPPROTO_IPV6, syscall.IPV6_UNICAST_HOPS, ttl)
+				if opErr == nil {
+					opErr = syscall.Bind(s, &syscall.SockaddrInet6{})
+				}
+			} else {
+				opErr = syscall.SetsockoptInt(s, syscall.IPPROTO_IP, syscall.IP_TTL, ttl)
+				if opErr == nil {
+					opErr = syscall.Bind(s, &syscall.SockaddrInet4{})
+				}
+			}
+			if opErr != nil {
+				return
+			}
+
+			sa, err := syscall.Getsockname(s)
+			if err != nil {
+				opErr = err
+				return
+			}
+			switch addr := sa.(type) {
+			case *syscall.SockaddrInet4:
+				onBind(addr.Port)
+			case *syscall.SockaddrInet6:
+				onBind(addr.Port)
+			}
+		})
+		if err != nil {
+			return err
+		}
+		return opErr
+	}
+}
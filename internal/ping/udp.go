@@ -0,0 +1,181 @@
+package ping
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"net_detect/internal/models"
+	"net_detect/internal/reflector"
+	"net_detect/utils"
+)
+
+const reportRetries = 3
+
+// UDPPinger UDP反射探测接口
+type UDPPinger interface {
+	Ping(ctx context.Context, target models.UDPPingTarget) models.UDPPingResult
+}
+
+// DefaultUDPPinger 向对端UDP反射端发送带序号和时间戳的报文，
+// 结合反射端的会话统计分别计算正向和反向的丢包、乱序和重复
+type DefaultUDPPinger struct {
+	config Config
+	port   int
+}
+
+func NewUDPPinger(config Config, port int) *DefaultUDPPinger {
+	if port <= 0 {
+		port = reflector.DefaultPort
+	}
+	return &DefaultUDPPinger{config: config, port: port}
+}
+
+func (p *DefaultUDPPinger) Ping(ctx context.Context, target models.UDPPingTarget) (result models.UDPPingResult) {
+	result = models.UDPPingResult{
+		TargetIP:   target.IP,
+		TargetNode: target.NodeName,
+		TargetHost: target.HostName,
+		Tags:       target.Tags,
+		IPVersion:  utils.GetIPVersion(target.IP),
+	}
+	defer func() { result.Timestamp = time.Now() }()
+
+	port := target.Port
+	if port == 0 {
+		port = p.port
+	}
+	count := p.config.Count
+	if target.Count > 0 {
+		count = target.Count
+	}
+
+	conn, err := net.Dial("udp", net.JoinHostPort(target.IP, strconv.Itoa(port)))
+	if err != nil {
+		result.Error = fmt.Sprintf("创建连接失败: %v", err)
+		return result
+	}
+	defer conn.Close()
+	result.SourceIP = conn.LocalAddr().(*net.UDPAddr).IP.String()
+
+	sessionID := rand.Uint64()
+	var (
+		mu      sync.Mutex
+		seen    = make(map[uint32]struct{}, count)
+		maxSeq  uint32
+		rtts    = make([]time.Duration, 0, count)
+		reports = make(chan reflector.SessionStats, 1)
+		done    = make(chan struct{})
+	)
+
+	// 接收应答
+	go func() {
+		defer close(done)
+		buf := make([]byte, 65535)
+		for {
+			n, err := conn.Read(buf)
+			if err != nil {
+				if transientReadError(err) {
+					continue
+				}
+				return
+			}
+			now := time.Now().UnixNano()
+			var pkt reflector.Packet
+			if pkt.Unmarshal(buf[:n]) != nil || pkt.Session != sessionID {
+				continue
+			}
+
+			switch pkt.Type {
+			case reflector.TypeProbeReply:
+				mu.Lock()
+				if _, dup := seen[pkt.Seq]; dup {
+					result.RevDuplicates++
+				} else {
+					seen[pkt.Seq] = struct{}{}
+					if len(seen) > 1 && pkt.Seq < maxSeq {
+						result.RevReordered++
+					}
+					if pkt.Seq > maxSeq {
+						maxSeq = pkt.Seq
+					}
+					// 扣除反射端处理耗时
+					rtts = append(rtts, time.Duration(now-pkt.SendTime-(pkt.TxTime-pkt.RxTime)))
+				}
+				mu.Unlock()
+			case reflector.TypeReportReply:
+				select {
+				case reports <- pkt.Stats:
+				default:
+				}
+			}
+		}
+	}()
+
+	for seq := 0; seq < count; seq++ {
+		if seq > 0 && sleepContext(ctx, p.config.Interval) != nil {
+			break
+		}
+		pkt := reflector.Packet{
+			Type:     reflector.TypeProbe,
+			Session:  sessionID,
+			Seq:      uint32(seq),
+			SendTime: time.Now().UnixNano(),
+		}
+		if _, err := conn.Write(pkt.Marshal(target.Size)); err != nil {
+			result.Error = fmt.Sprintf("发送报文失败: %v", err)
+			continue
+		}
+		result.PacketsSent++
+	}
+
+	// 等待最后的应答到达后请求反射端统计，ctx结束时不再等待
+	sleepContext(ctx, p.config.Timeout)
+	var stats reflector.SessionStats
+	for i := 0; i < reportRetries && !result.ReportOK && ctx.Err() == nil; i++ {
+		req := reflector.Packet{Type: reflector.TypeReport, Session: sessionID}
+		conn.Write(req.Marshal(reflector.HeaderSize))
+		select {
+		case stats = <-reports:
+			result.ReportOK = true
+		case <-time.After(p.config.Timeout):
+		case <-ctx.Done():
+		}
+	}
+	conn.Close()
+	<-done
+
+	result.PacketsRecv = len(seen)
+	if result.ReportOK {
+		result.FwdRecv = int(stats.Received)
+		result.FwdLoss = result.PacketsSent - int(stats.Received)
+		result.FwdReordered = int(stats.Reordered)
+		result.FwdDuplicates = int(stats.Duplicates)
+		result.RevLoss = int(stats.Replied) - result.PacketsRecv
+	} else if result.Error == "" && ctx.Err() != nil {
+		result.Error = fmt.Sprintf("探测中断: %v", ctx.Err())
+	} else if result.Error == "" {
+		result.Error = "未获取到反射端统计"
+	}
+
+	rttStats := calcRttStats(rtts)
+	result.MinRtt = rttStats.Min
+	result.MaxRtt = rttStats.Max
+	result.AvgRtt = rttStats.Avg
+	result.StdDevRtt = rttStats.StdDev
+	return result
+}
+
+// transientReadError 已连接的UDP socket收到ICMP差错(如端口不可达)后，下一次读会返回
+// ECONNREFUSED等错误，socket本身仍可用，不应因此停止接收
+func transientReadError(err error) bool {
+	return errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.EHOSTUNREACH) ||
+		errors.Is(err, syscall.ENETUNREACH)
+}
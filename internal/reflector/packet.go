@@ -0,0 +1,84 @@
+package reflector
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// 报文类型
+const (
+	TypeProbe       uint8 = 1 // 探测报文
+	TypeProbeReply  uint8 = 2 // 探测应答
+	TypeReport      uint8 = 3 // 请求会话统计
+	TypeReportReply uint8 = 4 // 会话统计应答
+)
+
+const (
+	magic      uint32 = 0x4e445552 // "NDUR"
+	HeaderSize        = 64
+
+	// DefaultPort 目标未指定端口时发送端使用的反射端口
+	DefaultPort = 8862
+)
+
+var ErrInvalidPacket = errors.New("invalid reflector packet")
+
+// Packet 反射报文，时间戳均为UnixNano
+//
+//	0       4    5     8        16    20      24        32       40       48
+//	| magic | type| pad | session | seq | pad | sendTime | rxTime | txTime | stats(16) |
+type Packet struct {
+	Type     uint8
+	Session  uint64
+	Seq      uint32
+	SendTime int64 // 发送端发送时间
+	RxTime   int64 // 反射端接收时间
+	TxTime   int64 // 反射端发送时间
+	Stats    SessionStats
+}
+
+// SessionStats 反射端统计的正向（发送端->反射端）会话数据
+type SessionStats struct {
+	Received   uint32 // 收到的不重复报文数
+	Duplicates uint32 // 重复报文数
+	Reordered  uint32 // 乱序报文数
+	Replied    uint32 // 发出的应答数
+}
+
+// Marshal 编码报文，size小于头部长度时按头部长度编码，多余部分填充0
+func (p *Packet) Marshal(size int) []byte {
+	if size < HeaderSize {
+		size = HeaderSize
+	}
+	b := make([]byte, size)
+	binary.BigEndian.PutUint32(b[0:], magic)
+	b[4] = p.Type
+	binary.BigEndian.PutUint64(b[8:], p.Session)
+	binary.BigEndian.PutUint32(b[16:], p.Seq)
+	binary.BigEndian.PutUint64(b[24:], uint64(p.SendTime))
+	binary.BigEndian.PutUint64(b[32:], uint64(p.RxTime))
+	binary.BigEndian.PutUint64(b[40:], uint64(p.TxTime))
+	binary.BigEndian.PutUint32(b[48:], p.Stats.Received)
+	binary.BigEndian.PutUint32(b[52:], p.Stats.Duplicates)
+	binary.BigEndian.PutUint32(b[56:], p.Stats.Reordered)
+	binary.BigEndian.PutUint32(b[60:], p.Stats.Replied)
+	return b
+}
+
+// Unmarshal 解码报文
+func (p *Packet) Unmarshal(b []byte) error {
+	if len(b) < HeaderSize || binary.BigEndian.Uint32(b[0:]) != magic {
+		return ErrInvalidPacket
+	}
+	p.Type = b[4]
+	p.Session = binary.BigEndian.Uint64(b[8:])
+	p.Seq = binary.BigEndian.Uint32(b[16:])
+	p.SendTime = int64(binary.BigEndian.Uint64(b[24:]))
+	p.RxTime = int64(binary.BigEndian.Uint64(b[32:]))
+	p.TxTime = int64(binary.BigEndian.Uint64(b[40:]))
+	p.Stats.Received = binary.BigEndian.Uint32(b[48:])
+	p.Stats.Duplicates = binary.BigEndian.Uint32(b[52:])
+	p.Stats.Reordered = binary.BigEndian.Uint32(b[56:])
+	p.Stats.Replied = binary.BigEndian.Uint32(b[60:])
+	return nil
+}
@@ -0,0 +1,219 @@
+package reflector
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"sync"
+	"time"
+)
+
+const (
+	sessionTTL  = 2 * time.Minute
+	maxSessions = 10000
+	// 连续读错误时的退避上限
+	maxReadBackoff = time.Second
+	// dupWindow 重复检测窗口，只记录最大序号之前这么多个序号的收包情况
+	dupWindow = 1024
+)
+
+// seqWindow 以位图记录最近dupWindow个序号是否已收到，内存占用固定
+type seqWindow struct {
+	bits    [dupWindow / 64]uint64
+	max     uint32
+	started bool
+}
+
+// add 记录序号，重复时返回false。比窗口更早的序号无法判断，按新报文处理
+func (w *seqWindow) add(seq uint32) bool {
+	switch {
+	case !w.started:
+		w.started = true
+		w.max = seq
+	case seq > w.max:
+		// 窗口前移，清除新进入窗口的位置上的旧记录
+		if seq-w.max >= dupWindow {
+			w.bits = [dupWindow / 64]uint64{}
+		} else {
+			for s := w.max + 1; s != seq; s++ {
+				w.clear(s)
+			}
+		}
+		w.max = seq
+	case w.max-seq >= dupWindow:
+		return true
+	case w.test(seq):
+		return false
+	}
+	w.set(seq)
+	return true
+}
+
+func (w *seqWindow) test(seq uint32) bool {
+	i := seq % dupWindow
+	return w.bits[i/64]&(1<<(i%64)) != 0
+}
+
+func (w *seqWindow) set(seq uint32) {
+	i := seq % dupWindow
+	w.bits[i/64] |= 1 << (i % 64)
+}
+
+func (w *seqWindow) clear(seq uint32) {
+	i := seq % dupWindow
+	w.bits[i/64] &^= 1 << (i % 64)
+}
+
+// session 单个发送端会话的状态
+type session struct {
+	seen     seqWindow
+	maxSeq   uint32
+	stats    SessionStats
+	lastSeen time.Time
+}
+
+// UDPReflector UDP反射端，对探测报文回填时间戳后原路返回，
+// 并按会话统计正向丢包、乱序和重复
+type UDPReflector struct {
+	addr     string
+	conn     *net.UDPConn
+	sessions map[string]*session
+	mu       sync.Mutex
+	stopCh   chan struct{}
+}
+
+func NewUDPReflector(addr string) *UDPReflector {
+	return &UDPReflector{
+		addr:     addr,
+		sessions: make(map[string]*session),
+		stopCh:   make(chan struct{}),
+	}
+}
+
+// Start 监听并处理报文，直到Stop被调用
+func (r *UDPReflector) Start() error {
+	udpAddr, err := net.ResolveUDPAddr("udp", r.addr)
+	if err != nil {
+		return err
+	}
+	conn, err := net.ListenUDP("udp", udpAddr)
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	r.conn = conn
+	r.mu.Unlock()
+	log.Printf("UDP reflector listening on %s", conn.LocalAddr())
+
+	go r.expireSessions()
+
+	buf := make([]byte, 65535)
+	var backoff time.Duration
+	for {
+		n, peer, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			select {
+			case <-r.stopCh:
+				return nil
+			default:
+			}
+			if errors.Is(err, net.ErrClosed) {
+				return err
+			}
+			// 持续出错时逐步退避，避免空转刷日志
+			backoff = min(max(2*backoff, 10*time.Millisecond), maxReadBackoff)
+			log.Printf("UDP reflector read error: %v, retry in %v", err, backoff)
+			time.Sleep(backoff)
+			continue
+		}
+		backoff = 0
+		rxTime := time.Now().UnixNano()
+
+		var pkt Packet
+		if err := pkt.Unmarshal(buf[:n]); err != nil {
+			continue
+		}
+		if reply := r.handle(&pkt, peer, rxTime, n); reply != nil {
+			conn.WriteToUDP(reply, peer)
+		}
+	}
+}
+
+func (r *UDPReflector) Stop() {
+	close(r.stopCh)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.conn != nil {
+		r.conn.Close()
+	}
+}
+
+// handle 更新会话统计并生成应答，重复报文不回复，保证发送端统计的重复只来自反向路径
+func (r *UDPReflector) handle(pkt *Packet, peer *net.UDPAddr, rxTime int64, size int) []byte {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := sessionKey(peer, pkt.Session)
+	s, ok := r.sessions[key]
+	if !ok {
+		if len(r.sessions) >= maxSessions {
+			return nil
+		}
+		s = &session{}
+		r.sessions[key] = s
+	}
+	s.lastSeen = time.Now()
+
+	switch pkt.Type {
+	case TypeProbe:
+		if !s.seen.add(pkt.Seq) {
+			s.stats.Duplicates++
+			return nil
+		}
+		s.stats.Received++
+		if s.stats.Received > 1 && pkt.Seq < s.maxSeq {
+			s.stats.Reordered++
+		}
+		if pkt.Seq > s.maxSeq {
+			s.maxSeq = pkt.Seq
+		}
+		s.stats.Replied++
+
+		pkt.Type = TypeProbeReply
+		pkt.RxTime = rxTime
+		pkt.TxTime = time.Now().UnixNano()
+		return pkt.Marshal(size)
+	case TypeReport:
+		pkt.Type = TypeReportReply
+		pkt.Stats = s.stats
+		pkt.RxTime = rxTime
+		pkt.TxTime = time.Now().UnixNano()
+		return pkt.Marshal(HeaderSize)
+	}
+	return nil
+}
+
+// expireSessions 定期清理过期会话
+func (r *UDPReflector) expireSessions() {
+	ticker := time.NewTicker(sessionTTL / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.stopCh:
+			return
+		case <-ticker.C:
+			r.mu.Lock()
+			for key, s := range r.sessions {
+				if time.Since(s.lastSeen) > sessionTTL {
+					delete(r.sessions, key)
+				}
+			}
+			r.mu.Unlock()
+		}
+	}
+}
+
+func sessionKey(peer *net.UDPAddr, id uint64) string {
+	return fmt.Sprintf("%s/%d", peer, id)
+}
@@ -0,0 +1,106 @@
+// Package stamp 实现RFC 8762 STAMP无认证模式的会话反射端和报文格式
+package stamp
+
+import (
+	"encoding/binary"
+	"errors"
+	"time"
+
+	"net_detect/utils"
+)
+
+const (
+	// DefaultPort RFC 8762 规定的STAMP端口
+	DefaultPort = 862
+	// PacketSize 无认证模式的基础报文长度
+	PacketSize = 44
+)
+
+var ErrShortPacket = errors.New("stamp packet too short")
+
+// SenderPacket 无认证模式的发送端报文
+//
+//	0-3 序号 | 4-11 时间戳 | 12-13 误差估计 | 14-43 MBZ
+type SenderPacket struct {
+	Seq           uint32
+	Timestamp     time.Time
+	ErrorEstimate uint16
+}
+
+func (p *SenderPacket) Marshal(size int) []byte {
+	if size < PacketSize {
+		size = PacketSize
+	}
+	b := make([]byte, size)
+	binary.BigEndian.PutUint32(b[0:], p.Seq)
+	binary.BigEndian.PutUint64(b[4:], utils.ToNTPTime(p.Timestamp))
+	binary.BigEndian.PutUint16(b[12:], p.ErrorEstimate)
+	return b
+}
+
+func (p *SenderPacket) Unmarshal(b []byte) error {
+	if len(b) < PacketSize {
+		return ErrShortPacket
+	}
+	p.Seq = binary.BigEndian.Uint32(b[0:])
+	p.Timestamp = utils.FromNTPTime(binary.BigEndian.Uint64(b[4:]))
+	p.ErrorEstimate = binary.BigEndian.Uint16(b[12:])
+	return nil
+}
+
+// ReflectorPacket 无认证模式的反射端报文
+//
+//	0-3 序号 | 4-11 发送时间戳 | 12-13 误差估计 | 14-15 MBZ | 16-23 接收时间戳 |
+//	24-27 发送端序号 | 28-35 发送端时间戳 | 36-37 发送端误差估计 | 38-39 MBZ | 40 发送端TTL | 41-43 MBZ
+type ReflectorPacket struct {
+	Seq                 uint32
+	Timestamp           time.Time
+	ErrorEstimate       uint16
+	ReceiveTimestamp    time.Time
+	SenderSeq           uint32
+	SenderTimestamp     time.Time
+	SenderErrorEstimate uint16
+	SenderTTL           uint8
+}
+
+func (p *ReflectorPacket) Marshal(size int) []byte {
+	if size < PacketSize {
+		size = PacketSize
+	}
+	b := make([]byte, size)
+	binary.BigEndian.PutUint32(b[0:], p.Seq)
+	binary.BigEndian.PutUint64(b[4:], utils.ToNTPTime(p.Timestamp))
+	binary.BigEndian.PutUint16(b[12:], p.ErrorEstimate)
+	binary.BigEndian.PutUint64(b[16:], utils.ToNTPTime(p.ReceiveTimestamp))
+	binary.BigEndian.PutUint32(b[24:], p.SenderSeq)
+	binary.BigEndian.PutUint64(b[28:], utils.ToNTPTime(p.SenderTimestamp))
+	binary.BigEndian.PutUint16(b[36:], p.SenderErrorEstimate)
+	b[40] = p.SenderTTL
+	return b
+}
+
+func (p *ReflectorPacket) Unmarshal(b []byte) error {
+	if len(b) < PacketSize {
+		return ErrShortPacket
+	}
+	p.Seq = binary.BigEndian.Uint32(b[0:])
+	p.Timestamp = utils.FromNTPTime(binary.BigEndian.Uint64(b[4:]))
+	p.ErrorEstimate = binary.BigEndian.Uint16(b[12:])
+	p.ReceiveTimestamp = utils.FromNTPTime(binary.BigEndian.Uint64(b[16:]))
+	p.SenderSeq = binary.BigEndian.Uint32(b[24:])
+	p.SenderTimestamp = utils.FromNTPTime(binary.BigEndian.Uint64(b[28:]))
+	p.SenderErrorEstimate = binary.BigEndian.Uint16(b[36:])
+	p.SenderTTL = b[40]
+	return nil
+}
+
+// ErrorEstimate 构造误差估计字段(RFC 4656 4.1.2)：S位表示时钟已同步，
+// Z位为0表示NTP格式，误差约为 Multiplier*2^(Scale-32) 秒，此处固定为约1ms
+func ErrorEstimate(synced bool) uint16 {
+	const scale, multiplier = 22, 1
+	v := uint16(scale)<<8 | multiplier
+	if synced {
+		v |= 1 << 15
+	}
+	return v
+}
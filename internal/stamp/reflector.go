@@ -0,0 +1,158 @@
+package stamp
+
+import (
+	"errors"
+	"log"
+	"net"
+	"sync"
+	"time"
+
+	"net_detect/internal/clock"
+
+	"golang.org/x/net/ipv4"
+)
+
+const (
+	sessionTTL  = 2 * time.Minute
+	maxSessions = 10000
+	// 连续读错误时的退避上限
+	maxReadBackoff = time.Second
+)
+
+// session 有状态反射端的会话，反射序号按会话内收到的报文递增
+type session struct {
+	seq      uint32
+	lastSeen time.Time
+}
+
+// Reflector STAMP会话反射端（无认证、有状态模式）
+type Reflector struct {
+	addr     string
+	conn     *net.UDPConn
+	sessions map[string]*session
+	mu       sync.Mutex
+	stopCh   chan struct{}
+}
+
+func NewReflector(addr string) *Reflector {
+	return &Reflector{
+		addr:     addr,
+		sessions: make(map[string]*session),
+		stopCh:   make(chan struct{}),
+	}
+}
+
+// Start 监听并反射STAMP报文，直到Stop被调用
+func (r *Reflector) Start() error {
+	udpAddr, err := net.ResolveUDPAddr("udp", r.addr)
+	if err != nil {
+		return err
+	}
+	conn, err := net.ListenUDP("udp", udpAddr)
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	r.conn = conn
+	r.mu.Unlock()
+	log.Printf("STAMP reflector listening on %s", conn.LocalAddr())
+
+	// 读取收到报文的TTL，用于填写Ses-Sender TTL
+	pconn := ipv4.NewPacketConn(conn)
+	if err := pconn.SetControlMessage(ipv4.FlagTTL, true); err != nil {
+		log.Printf("STAMP reflector cannot read TTL: %v", err)
+	}
+
+	go r.expireSessions()
+
+	buf := make([]byte, 65535)
+	var backoff time.Duration
+	for {
+		n, cm, peer, err := pconn.ReadFrom(buf)
+		if err != nil {
+			select {
+			case <-r.stopCh:
+				return nil
+			default:
+			}
+			if errors.Is(err, net.ErrClosed) {
+				return err
+			}
+			// 持续出错时逐步退避，避免空转刷日志
+			backoff = min(max(2*backoff, 10*time.Millisecond), maxReadBackoff)
+			log.Printf("STAMP reflector read error: %v, retry in %v", err, backoff)
+			time.Sleep(backoff)
+			continue
+		}
+		backoff = 0
+		rxTime := time.Now()
+
+		var req SenderPacket
+		if err := req.Unmarshal(buf[:n]); err != nil {
+			continue
+		}
+		reply := ReflectorPacket{
+			Seq:                 r.nextSeq(peer, req.Seq),
+			ErrorEstimate:       ErrorEstimate(clock.Synced()),
+			ReceiveTimestamp:    rxTime,
+			SenderSeq:           req.Seq,
+			SenderTimestamp:     req.Timestamp,
+			SenderErrorEstimate: req.ErrorEstimate,
+		}
+		if cm != nil {
+			reply.SenderTTL = uint8(cm.TTL)
+		}
+		reply.Timestamp = time.Now()
+		// 应答长度与请求一致，保证双向报文大小对称
+		conn.WriteTo(reply.Marshal(n), peer)
+	}
+}
+
+func (r *Reflector) Stop() {
+	close(r.stopCh)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.conn != nil {
+		r.conn.Close()
+	}
+}
+
+// nextSeq 返回会话内的反射序号，会话数达到上限时新的发送端按无状态模式回填发送序号
+func (r *Reflector) nextSeq(peer net.Addr, senderSeq uint32) uint32 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := peer.String()
+	s, ok := r.sessions[key]
+	if !ok {
+		if len(r.sessions) >= maxSessions {
+			return senderSeq
+		}
+		s = &session{}
+		r.sessions[key] = s
+	} else {
+		s.seq++
+	}
+	s.lastSeen = time.Now()
+	return s.seq
+}
+
+// expireSessions 定期清理过期会话
+func (r *Reflector) expireSessions() {
+	ticker := time.NewTicker(sessionTTL / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.stopCh:
+			return
+		case <-ticker.C:
+			r.mu.Lock()
+			for key, s := range r.sessions {
+				if time.Since(s.lastSeen) > sessionTTL {
+					delete(r.sessions, key)
+				}
+			}
+			r.mu.Unlock()
+		}
+	}
+}
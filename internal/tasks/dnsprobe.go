@@ -0,0 +1,328 @@
+package tasks
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"net_detect/internal/models"
+	"net_detect/internal/storage"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+const (
+	defaultDNSTimeout = 3 * time.Second
+	dnsUDPBufferSize  = 4096
+)
+
+var dnsQueryTypes = map[string]dnsmessage.Type{
+	"A":     dnsmessage.TypeA,
+	"AAAA":  dnsmessage.TypeAAAA,
+	"CNAME": dnsmessage.TypeCNAME,
+	"SRV":   dnsmessage.TypeSRV,
+	"TXT":   dnsmessage.TypeTXT,
+}
+
+// DNSProbeTask DNS解析探测任务，向指定解析器查询并校验应答
+type DNSProbeTask struct {
+	storage storage.ResultStorage
+}
+
+func NewDNSProbeTask(storage storage.ResultStorage) *DNSProbeTask {
+	return &DNSProbeTask{storage: storage}
+}
+
+func (t *DNSProbeTask) Name() string {
+	return "dnsProbe"
+}
+
+func (t *DNSProbeTask) Execute(ctx context.Context, metricName string, params []interface{}) (Summary, error) {
+	targets, err := t.parseParams(params)
+	if err != nil {
+		return Summary{}, err
+	}
+
+	var wg sync.WaitGroup
+	results := make([]models.DNSProbeResult, len(targets))
+
+	// 并发执行探测
+	for i, target := range targets {
+		wg.Add(1)
+		go func(index int, target models.DNSProbeTarget) {
+			defer wg.Done()
+			results[index] = t.probe(ctx, target)
+		}(i, target)
+	}
+	wg.Wait()
+
+	return summarize(results, func(r models.DNSProbeResult) string { return r.Error }), t.storage.Store(t.resultInfulxDBFormat(metricName, results))
+}
+
+func (t *DNSProbeTask) probe(ctx context.Context, target models.DNSProbeTarget) (result models.DNSProbeResult) {
+	result = models.DNSProbeResult{
+		TargetNode: target.NodeName,
+		TargetHost: target.HostName,
+		Name:       target.Name,
+		Type:       target.Type,
+		Protocol:   target.Protocol,
+		Tags:       target.Tags,
+	}
+	defer func() { result.Timestamp = time.Now() }()
+
+	resolver := target.Resolver
+	if _, _, err := net.SplitHostPort(resolver); err != nil {
+		resolver = net.JoinHostPort(resolver, "53")
+	}
+	result.ResolverIP, _, _ = net.SplitHostPort(resolver)
+
+	timeout := target.Timeout
+	if timeout <= 0 {
+		timeout = defaultDNSTimeout
+	}
+
+	qtype := dnsQueryTypes[target.Type]
+	query, id, err := buildDNSQuery(target.Name, qtype)
+	if err != nil {
+		result.Error = fmt.Sprintf("构建查询失败: %v", err)
+		return result
+	}
+
+	start := time.Now()
+	resp, sourceIP, err := exchangeDNS(ctx, target.Protocol, resolver, query, timeout)
+	// UDP应答被截断时使用TCP重试
+	if err == nil && target.Protocol == "udp" && isTruncated(resp) {
+		result.Truncated = true
+		resp, sourceIP, err = exchangeDNS(ctx, "tcp", resolver, query, timeout)
+	}
+	result.Latency = msSince(start)
+	result.SourceIP = sourceIP
+	if err != nil {
+		result.Error = fmt.Sprintf("查询失败: %v", err)
+		return result
+	}
+
+	var msg dnsmessage.Message
+	if err := msg.Unpack(resp); err != nil {
+		result.Error = fmt.Sprintf("解析应答失败: %v", err)
+		return result
+	}
+	if msg.Header.ID != id {
+		result.Error = fmt.Sprintf("应答ID不匹配: %d != %d", msg.Header.ID, id)
+		return result
+	}
+
+	result.Rcode = int(msg.Header.RCode)
+	result.RcodeName = strings.TrimPrefix(msg.Header.RCode.String(), "RCode")
+	result.AnswerCount = len(msg.Answers)
+	for _, answer := range msg.Answers {
+		if answer.Header.Type != qtype {
+			continue
+		}
+		result.Answers = append(result.Answers, formatDNSAnswer(answer.Body))
+	}
+	sort.Strings(result.Answers)
+	result.Match = len(target.Expected) == 0 || sameAnswerSet(result.Answers, target.Expected)
+	return result
+}
+
+// buildDNSQuery 构建带EDNS0的递归查询报文
+func buildDNSQuery(name string, qtype dnsmessage.Type) ([]byte, uint16, error) {
+	qname, err := dnsmessage.NewName(dnsFQDN(name))
+	if err != nil {
+		return nil, 0, err
+	}
+
+	id := uint16(rand.Intn(1 << 16))
+	msg := dnsmessage.Message{
+		Header: dnsmessage.Header{ID: id, RecursionDesired: true},
+		Questions: []dnsmessage.Question{
+			{Name: qname, Type: qtype, Class: dnsmessage.ClassINET},
+		},
+	}
+
+	var opt dnsmessage.ResourceHeader
+	if err := opt.SetEDNS0(dnsUDPBufferSize, dnsmessage.RCodeSuccess, false); err != nil {
+		return nil, 0, err
+	}
+	msg.Additionals = []dnsmessage.Resource{{Header: opt, Body: &dnsmessage.OPTResource{}}}
+
+	query, err := msg.Pack()
+	return query, id, err
+}
+
+// exchangeDNS 发送查询并读取应答，返回应答报文和本端IP
+func exchangeDNS(ctx context.Context, protocol, resolver string, query []byte, timeout time.Duration) ([]byte, string, error) {
+	dialer := net.Dialer{Timeout: timeout}
+	conn, err := dialer.DialContext(ctx, protocol, resolver)
+	if err != nil {
+		return nil, "", err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(timeout))
+	stop := context.AfterFunc(ctx, func() { conn.SetDeadline(time.Now()) })
+	defer stop()
+
+	sourceIP, _, _ := net.SplitHostPort(conn.LocalAddr().String())
+
+	if protocol == "udp" {
+		if _, err := conn.Write(query); err != nil {
+			return nil, sourceIP, err
+		}
+		buf := make([]byte, dnsUDPBufferSize)
+		n, err := conn.Read(buf)
+		if err != nil {
+			return nil, sourceIP, err
+		}
+		return buf[:n], sourceIP, nil
+	}
+
+	// TCP报文前缀两字节长度
+	msg := make([]byte, 2+len(query))
+	binary.BigEndian.PutUint16(msg, uint16(len(query)))
+	copy(msg[2:], query)
+	if _, err := conn.Write(msg); err != nil {
+		return nil, sourceIP, err
+	}
+	var length [2]byte
+	if _, err := io.ReadFull(conn, length[:]); err != nil {
+		return nil, sourceIP, err
+	}
+	resp := make([]byte, binary.BigEndian.Uint16(length[:]))
+	if _, err := io.ReadFull(conn, resp); err != nil {
+		return nil, sourceIP, err
+	}
+	return resp, sourceIP, nil
+}
+
+func isTruncated(resp []byte) bool {
+	var p dnsmessage.Parser
+	header, err := p.Start(resp)
+	return err == nil && header.Truncated
+}
+
+// formatDNSAnswer 将应答记录转为可比较的字符串
+func formatDNSAnswer(body dnsmessage.ResourceBody) string {
+	switch r := body.(type) {
+	case *dnsmessage.AResource:
+		return net.IP(r.A[:]).String()
+	case *dnsmessage.AAAAResource:
+		return net.IP(r.AAAA[:]).String()
+	case *dnsmessage.CNAMEResource:
+		return dnsFQDN(r.CNAME.String())
+	case *dnsmessage.SRVResource:
+		return fmt.Sprintf("%d %d %d %s", r.Priority, r.Weight, r.Port, dnsFQDN(r.Target.String()))
+	case *dnsmessage.TXTResource:
+		return strings.Join(r.TXT, "")
+	default:
+		return body.GoString()
+	}
+}
+
+// sameAnswerSet 判断应答与期望集合是否一致，忽略顺序和大小写
+func sameAnswerSet(answers, expected []string) bool {
+	want := make(map[string]struct{}, len(expected))
+	for _, e := range expected {
+		want[normalizeDNSAnswer(e)] = struct{}{}
+	}
+	got := make(map[string]struct{}, len(answers))
+	for _, a := range answers {
+		got[normalizeDNSAnswer(a)] = struct{}{}
+	}
+	if len(got) != len(want) {
+		return false
+	}
+	for a := range got {
+		if _, ok := want[a]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func normalizeDNSAnswer(a string) string {
+	a = strings.ToLower(strings.TrimSpace(a))
+	if ip := net.ParseIP(a); ip != nil {
+		return ip.String()
+	}
+	return strings.TrimSuffix(a, ".")
+}
+
+func dnsFQDN(name string) string {
+	if strings.HasSuffix(name, ".") {
+		return name
+	}
+	return name + "."
+}
+
+func (t *DNSProbeTask) resultInfulxDBFormat(metricName string, results []models.DNSProbeResult) []string {
+	var lines []string
+	for _, r := range results {
+		tags := sourceTags(r.SourceIP)
+		tags = append(tags, targetTags(r.ResolverIP, r.TargetNode, r.TargetHost)...)
+		tags = append(tags,
+			fmt.Sprintf("query_name=%s", escapeTag(r.Name)),
+			fmt.Sprintf("query_type=%s", r.Type),
+			fmt.Sprintf("protocol=%s", r.Protocol),
+		)
+		tags = appendTags(tags, r.Tags)
+
+		success := r.Error == "" && r.Rcode == int(dnsmessage.RCodeSuccess) && r.Match
+		fields := []string{
+			fmt.Sprintf("latency=%f", r.Latency),
+			fmt.Sprintf("answer_count=%di", r.AnswerCount),
+			fmt.Sprintf("truncated=%t", r.Truncated),
+			fmt.Sprintf("answers_match=%t", r.Match),
+			fmt.Sprintf("success=%t", success),
+			stringField("answers", strings.Join(r.Answers, ",")),
+		}
+		if r.Error == "" {
+			fields = append(fields,
+				fmt.Sprintf("rcode=%di", r.Rcode),
+				stringField("rcode_name", r.RcodeName),
+			)
+		} else {
+			fields = append(fields, stringField("error", r.Error))
+		}
+
+		lines = append(lines, formatLine(metricName, tags, fields, r.Timestamp))
+	}
+	return lines
+}
+
+func (t *DNSProbeTask) parseParams(params []interface{}) ([]models.DNSProbeTarget, error) {
+	targets, err := decodeParams[models.DNSProbeTarget](params)
+	if err != nil {
+		return nil, err
+	}
+
+	// 验证必要字段
+	for i := range targets {
+		target := &targets[i]
+		if target.Resolver == "" || target.Name == "" {
+			return nil, fmt.Errorf("resolver and name fields are required")
+		}
+		target.Type = strings.ToUpper(target.Type)
+		if target.Type == "" {
+			target.Type = "A"
+		}
+		if _, ok := dnsQueryTypes[target.Type]; !ok {
+			return nil, fmt.Errorf("unsupported record type %q for %s", target.Type, target.Name)
+		}
+		switch target.Protocol {
+		case "":
+			target.Protocol = "udp"
+		case "udp", "tcp":
+		default:
+			return nil, fmt.Errorf("unsupported protocol %q for %s", target.Protocol, target.Name)
+		}
+	}
+	return targets, nil
+}
@@ -0,0 +1,148 @@
+package tasks
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+
+	"net_detect/internal/models"
+	"net_detect/internal/ping"
+	"net_detect/internal/storage"
+)
+
+// ECMPProbeTask 多流探测任务，按流ID输出丢包和RTT，
+// 同时输出 <metricName>_summary 汇总受损流的数量，用于发现只影响部分ECMP路径的故障
+type ECMPProbeTask struct {
+	prober  ping.FlowProber
+	storage storage.ResultStorage
+}
+
+func NewECMPProbeTask(prober ping.FlowProber, storage storage.ResultStorage) *ECMPProbeTask {
+	return &ECMPProbeTask{
+		prober:  prober,
+		storage: storage,
+	}
+}
+
+func (t *ECMPProbeTask) Name() string {
+	return "ecmpProbe"
+}
+
+func (t *ECMPProbeTask) Execute(ctx context.Context, metricName string, params []interface{}) (Summary, error) {
+	targets, err := t.parseParams(params)
+	if err != nil {
+		return Summary{}, err
+	}
+
+	var wg sync.WaitGroup
+	results := make([]models.ECMPResult, len(targets))
+
+	// 并发执行探测
+	for i, target := range targets {
+		wg.Add(1)
+		go func(index int, target models.ECMPTarget) {
+			defer wg.Done()
+			results[index] = t.prober.Probe(ctx, target)
+		}(i, target)
+	}
+	wg.Wait()
+
+	return summarize(results, func(r models.ECMPResult) string { return r.Error }), t.storage.Store(t.resultInfulxDBFormat(metricName, results))
+}
+
+func (t *ECMPProbeTask) resultInfulxDBFormat(metricName string, results []models.ECMPResult) []string {
+	var lines []string
+	for _, r := range results {
+		tags := sourceTags(r.SourceIP)
+		tags = append(tags, targetTags(r.TargetIP, r.TargetNode, r.TargetHost)...)
+		tags = append(tags,
+			fmt.Sprintf("target_port=%d", r.TargetPort),
+			fmt.Sprintf("protocol=%s", r.Protocol),
+		)
+		tags = appendTags(tags, r.Tags)
+
+		var lossy, down int
+		minLoss, maxLoss := 1.0, 0.0
+		for _, f := range r.Flows {
+			loss := f.PacketsSent - f.PacketsRecv
+			lossRate := 0.0
+			if f.PacketsSent > 0 {
+				lossRate = float64(loss) / float64(f.PacketsSent)
+			}
+			if loss > 0 {
+				lossy++
+			}
+			if f.PacketsRecv == 0 {
+				down++
+			}
+			minLoss = min(minLoss, lossRate)
+			maxLoss = max(maxLoss, lossRate)
+
+			flowTags := append(tags[:len(tags):len(tags)], "flow_id="+strconv.Itoa(f.FlowID))
+			fields := []string{
+				fmt.Sprintf("src_port=%di", f.SrcPort),
+				fmt.Sprintf("flow_label=%di", f.FlowLabel),
+				fmt.Sprintf("packets_sent=%di", f.PacketsSent),
+				fmt.Sprintf("packets_recv=%di", f.PacketsRecv),
+				fmt.Sprintf("packets_loss=%di", loss),
+				fmt.Sprintf("loss_rate=%f", lossRate),
+				fmt.Sprintf("rtt_min=%f", f.MinRtt),
+				fmt.Sprintf("rtt_max=%f", f.MaxRtt),
+				fmt.Sprintf("rtt_avg=%f", f.AvgRtt),
+				fmt.Sprintf("rtt_std_dev=%f", f.StdDevRtt),
+				fmt.Sprintf("jitter=%f", f.Jitter),
+			}
+			if f.Error != "" {
+				fields = append(fields, stringField("error", f.Error))
+			}
+			lines = append(lines, formatLine(metricName, flowTags, fields, r.Timestamp))
+		}
+
+		if len(r.Flows) == 0 {
+			minLoss = 0
+		}
+		fields := []string{
+			fmt.Sprintf("flows=%di", len(r.Flows)),
+			fmt.Sprintf("flows_lossy=%di", lossy),
+			fmt.Sprintf("flows_down=%di", down),
+			fmt.Sprintf("loss_rate_min=%f", minLoss),
+			fmt.Sprintf("loss_rate_max=%f", maxLoss),
+		}
+		if r.Error != "" {
+			fields = append(fields, stringField("error", r.Error))
+		}
+		lines = append(lines, formatLine(metricName+"_summary", tags, fields, r.Timestamp))
+	}
+	return lines
+}
+
+func (t *ECMPProbeTask) parseParams(params []interface{}) ([]models.ECMPTarget, error) {
+	targets, err := decodeParams[models.ECMPTarget](params)
+	if err != nil {
+		return nil, err
+	}
+
+	// 验证必要字段
+	for _, target := range targets {
+		if target.IP == "" {
+			return nil, fmt.Errorf("IP field is required")
+		}
+		switch target.Protocol {
+		case "", "udp":
+		case "tcp":
+			if target.Port == 0 {
+				return nil, fmt.Errorf("port field is required for tcp target %s", target.IP)
+			}
+		default:
+			return nil, fmt.Errorf("invalid protocol %q for target %s", target.Protocol, target.IP)
+		}
+		if target.Port < 0 || target.Port > 65535 {
+			return nil, fmt.Errorf("invalid port %d for target %s", target.Port, target.IP)
+		}
+		if target.SrcPortBase < 0 || target.SrcPortBase+max(target.Flows, 1)-1 > 65535 {
+			return nil, fmt.Errorf("invalid srcPortBase %d for target %s", target.SrcPortBase, target.IP)
+		}
+	}
+	return targets, nil
+}
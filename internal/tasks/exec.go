@@ -0,0 +1,315 @@
+package tasks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+
+	"net_detect/internal/config"
+	"net_detect/internal/storage"
+	"net_detect/utils"
+)
+
+const (
+	defaultExecTimeout   = 30 * time.Second
+	defaultExecMaxOutput = 1 << 20
+	maxExecStderr        = 4096
+)
+
+var errOutputTooLarge = errors.New("output exceeds limit")
+
+// ExecTask 外部命令插件任务。命令由agent配置指定，Kafka下发的任务只能选择插件和传递参数，
+// 参数以JSON数组写入stdin，stdout可以是JSON或Influx行协议，结果统一补充source标签后存储，
+// 另外输出 <metricName>_exec 记录每次执行的耗时和状态
+type ExecTask struct {
+	plugin  config.ExecPluginConfig
+	storage storage.ResultStorage
+}
+
+func NewExecTask(plugin config.ExecPluginConfig, storage storage.ResultStorage) *ExecTask {
+	if plugin.Timeout <= 0 {
+		plugin.Timeout = defaultExecTimeout
+	}
+	if plugin.MaxOutput <= 0 {
+		plugin.MaxOutput = defaultExecMaxOutput
+	}
+	return &ExecTask{
+		plugin:  plugin,
+		storage: storage,
+	}
+}
+
+func (t *ExecTask) Name() string {
+	return t.plugin.Name
+}
+
+func (t *ExecTask) Execute(ctx context.Context, metricName string, params []interface{}) (Summary, error) {
+	if params == nil {
+		params = []interface{}{}
+	}
+	input, err := json.Marshal(params)
+	if err != nil {
+		return Summary{}, fmt.Errorf("failed to marshal params: %v", err)
+	}
+
+	start := time.Now()
+	output, exitCode, runErr := t.run(ctx, metricName, input)
+	duration := msSince(start)
+
+	var lines []string
+	if runErr == nil {
+		lines, runErr = t.parseOutput(metricName, output)
+	}
+
+	sourceIP, _ := utils.GetLocalIP("0.0.0.0")
+	tags := append(sourceTags(sourceIP), fmt.Sprintf("plugin=%s", escapeTag(t.plugin.Name)))
+	fields := []string{
+		fmt.Sprintf("duration=%f", duration),
+		fmt.Sprintf("exit_code=%di", exitCode),
+		fmt.Sprintf("lines=%di", len(lines)),
+		fmt.Sprintf("success=%t", runErr == nil),
+	}
+	if runErr != nil {
+		fields = append(fields, stringField("error", runErr.Error()))
+	}
+	lines = append(lines, formatLine(metricName+"_exec", tags, fields, time.Now()))
+
+	if err := t.storage.Store(lines); err != nil {
+		return Summary{Targets: len(params)}, err
+	}
+	return Summary{Targets: len(params)}, runErr
+}
+
+// run 执行命令，超时或输出超限时终止进程
+func (t *ExecTask) run(parent context.Context, metricName string, input []byte) ([]byte, int, error) {
+	ctx, cancel := context.WithTimeout(parent, t.plugin.Timeout)
+	defer cancel()
+
+	nodeName, _ := utils.GetNodeName()
+	hostName, _ := utils.GetHostName()
+	cmd := exec.CommandContext(ctx, t.plugin.Command, t.plugin.Args...)
+	cmd.Env = append(os.Environ(),
+		"NET_DETECT_TASK="+t.plugin.Name,
+		"NET_DETECT_METRIC="+metricName,
+		"NET_DETECT_NODE="+nodeName,
+		"NET_DETECT_HOST="+hostName,
+	)
+	cmd.Env = append(cmd.Env, t.plugin.Env...)
+	cmd.Stdin = bytes.NewReader(input)
+	// 子进程未退出但持有管道时，最多再等待1秒
+	cmd.WaitDelay = time.Second
+	setProcessGroup(cmd)
+
+	stdout := &limitedBuffer{limit: t.plugin.MaxOutput, onExceed: cancel}
+	stderr := &limitedBuffer{limit: maxExecStderr, truncate: true}
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	err := cmd.Run()
+	exitCode := -1
+	if cmd.ProcessState != nil {
+		exitCode = cmd.ProcessState.ExitCode()
+	}
+	switch {
+	case stdout.exceeded:
+		return nil, exitCode, fmt.Errorf("plugin %s: stdout %v of %d bytes", t.plugin.Name, errOutputTooLarge, t.plugin.MaxOutput)
+	case parent.Err() != nil:
+		return nil, exitCode, fmt.Errorf("plugin %s: interrupted: %v", t.plugin.Name, parent.Err())
+	case ctx.Err() == context.DeadlineExceeded:
+		return nil, exitCode, fmt.Errorf("plugin %s: timed out after %v", t.plugin.Name, t.plugin.Timeout)
+	case err != nil:
+		msg := strings.TrimSpace(stderr.buf.String())
+		if msg == "" {
+			return nil, exitCode, fmt.Errorf("plugin %s: %v", t.plugin.Name, err)
+		}
+		return nil, exitCode, fmt.Errorf("plugin %s: %v: %s", t.plugin.Name, err, msg)
+	}
+	return stdout.buf.Bytes(), exitCode, nil
+}
+
+// parseOutput 解析stdout，以 [ 或 { 开头时按JSON解析，否则按行协议解析
+func (t *ExecTask) parseOutput(metricName string, output []byte) ([]string, error) {
+	output = bytes.TrimSpace(output)
+	if len(output) == 0 {
+		return nil, nil
+	}
+	sourceIP, _ := utils.GetLocalIP("0.0.0.0")
+	tags := sourceTags(sourceIP)
+
+	if output[0] == '[' || output[0] == '{' {
+		return parseJSONPoints(metricName, output, tags)
+	}
+	return parseLineProtocol(output, tags)
+}
+
+// execPoint 插件JSON输出的单个数据点，measurement默认为任务的metricName，
+// timestamp为Unix纳秒，省略时使用当前时间
+type execPoint struct {
+	Measurement string                 `json:"measurement"`
+	Tags        map[string]string      `json:"tags"`
+	Fields      map[string]interface{} `json:"fields"`
+	Timestamp   int64                  `json:"timestamp"`
+}
+
+func parseJSONPoints(metricName string, output []byte, baseTags []string) ([]string, error) {
+	var points []execPoint
+	if output[0] == '{' {
+		var p execPoint
+		if err := json.Unmarshal(output, &p); err != nil {
+			return nil, fmt.Errorf("invalid json output: %v", err)
+		}
+		points = append(points, p)
+	} else if err := json.Unmarshal(output, &points); err != nil {
+		return nil, fmt.Errorf("invalid json output: %v", err)
+	}
+
+	lines := make([]string, 0, len(points))
+	for i, p := range points {
+		if len(p.Fields) == 0 {
+			return nil, fmt.Errorf("json point %d has no fields", i)
+		}
+		measurement := p.Measurement
+		if measurement == "" {
+			measurement = metricName
+		}
+		// 插件输出的名称可能包含空格、逗号或等号，需转义后才是合法的行协议
+		measurement = escapeMeasurement(measurement)
+
+		tags := appendTags(baseTags[:len(baseTags):len(baseTags)], p.Tags)
+		keys := make([]string, 0, len(p.Fields))
+		for k := range p.Fields {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		fields := make([]string, 0, len(keys))
+		for _, k := range keys {
+			key := escapeTag(k)
+			switch v := p.Fields[k].(type) {
+			case float64:
+				fields = append(fields, fmt.Sprintf("%s=%f", key, v))
+			case bool:
+				fields = append(fields, fmt.Sprintf("%s=%t", key, v))
+			case string:
+				fields = append(fields, stringField(key, v))
+			default:
+				return nil, fmt.Errorf("json point %d: unsupported type %T for field %s", i, v, k)
+			}
+		}
+
+		ts := time.Now()
+		if p.Timestamp > 0 {
+			ts = time.Unix(0, p.Timestamp)
+		}
+		lines = append(lines, formatLine(measurement, tags, fields, ts))
+	}
+	return lines, nil
+}
+
+// parseLineProtocol 校验行协议并在measurement后插入source标签，插件已自带source_node标签时保持原样
+func parseLineProtocol(output []byte, baseTags []string) ([]string, error) {
+	var lines []string
+	for i, line := range strings.Split(string(output), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		end := measurementEnd(line)
+		if end <= 0 || end >= len(line) {
+			return nil, fmt.Errorf("invalid line protocol at line %d: %q", i+1, line)
+		}
+		// 至少需要一个field
+		if !strings.Contains(line[end:], " ") || !strings.Contains(line[end:], "=") {
+			return nil, fmt.Errorf("invalid line protocol at line %d: %q", i+1, line)
+		}
+
+		if !hasTag(line[end:tagsEnd(line)], "source_node") {
+			line = line[:end] + "," + strings.Join(baseTags, ",") + line[end:]
+		}
+		lines = append(lines, line)
+	}
+	return lines, nil
+}
+
+// measurementEnd 返回measurement之后第一个未转义的逗号或空格的位置
+func measurementEnd(line string) int {
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case '\\':
+			i++
+		case ',', ' ':
+			return i
+		}
+	}
+	return -1
+}
+
+// tagsEnd 返回tag部分之后第一个未转义空格的位置
+func tagsEnd(line string) int {
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case '\\':
+			i++
+		case ' ':
+			return i
+		}
+	}
+	return len(line)
+}
+
+// hasTag tags为measurement之后以逗号开头的tag部分，按未转义的逗号切分后比较tag名
+func hasTag(tags, key string) bool {
+	start := 0
+	for i := 0; i <= len(tags); i++ {
+		if i < len(tags) && tags[i] == '\\' {
+			i++
+			continue
+		}
+		if i < len(tags) && tags[i] != ',' {
+			continue
+		}
+		// tag名中的等号已转义，第一个等号即为名和值的分隔
+		if k, _, ok := strings.Cut(tags[start:i], "="); ok && k == key {
+			return true
+		}
+		start = i + 1
+	}
+	return false
+}
+
+// limitedBuffer 限制写入大小的缓冲区，truncate为true时丢弃超出部分，否则写入失败并调用onExceed
+type limitedBuffer struct {
+	buf      bytes.Buffer
+	limit    int
+	truncate bool
+	exceeded bool
+	onExceed func()
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	remain := b.limit - b.buf.Len()
+	if len(p) <= remain {
+		return b.buf.Write(p)
+	}
+	if remain > 0 {
+		b.buf.Write(p[:remain])
+	}
+	if b.truncate {
+		return len(p), nil
+	}
+	if !b.exceeded {
+		b.exceeded = true
+		if b.onExceed != nil {
+			b.onExceed()
+		}
+	}
+	return 0, errOutputTooLarge
+}
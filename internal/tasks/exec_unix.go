@@ -0,0 +1,16 @@
+//go:build unix
+
+package tasks
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// setProcessGroup 插件在独立进程组中运行，超时时连同其子进程一起终止
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+}
@@ -0,0 +1,188 @@
+package tasks
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"net_detect/internal/models"
+	"net_detect/internal/storage"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+const defaultGRPCTimeout = 5 * time.Second
+
+// GRPCHealthTask gRPC健康检查任务，调用标准的 grpc.health.v1.Health/Check
+type GRPCHealthTask struct {
+	storage storage.ResultStorage
+}
+
+func NewGRPCHealthTask(storage storage.ResultStorage) *GRPCHealthTask {
+	return &GRPCHealthTask{storage: storage}
+}
+
+func (t *GRPCHealthTask) Name() string {
+	return "grpcHealth"
+}
+
+func (t *GRPCHealthTask) Execute(ctx context.Context, metricName string, params []interface{}) (Summary, error) {
+	targets, err := t.parseParams(params)
+	if err != nil {
+		return Summary{}, err
+	}
+
+	var wg sync.WaitGroup
+	results := make([]models.GRPCHealthResult, len(targets))
+
+	// 并发执行探测
+	for i, target := range targets {
+		wg.Add(1)
+		go func(index int, target models.GRPCHealthTarget) {
+			defer wg.Done()
+			results[index] = t.check(ctx, target)
+		}(i, target)
+	}
+	wg.Wait()
+
+	return summarize(results, func(r models.GRPCHealthResult) string { return r.Error }), t.storage.Store(t.resultInfulxDBFormat(metricName, results))
+}
+
+func (t *GRPCHealthTask) check(ctx context.Context, target models.GRPCHealthTarget) (result models.GRPCHealthResult) {
+	result = models.GRPCHealthResult{
+		TargetIP:   target.IP,
+		TargetNode: target.NodeName,
+		TargetHost: target.HostName,
+		Address:    target.Address,
+		Service:    target.Service,
+		Tags:       target.Tags,
+	}
+	defer func() { result.Timestamp = time.Now() }()
+
+	timeout := target.Timeout
+	if timeout <= 0 {
+		timeout = defaultGRPCTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	// 自定义拨号以记录建连耗时和两端地址，指定IP时直连该地址
+	var mu sync.Mutex
+	dialer := &net.Dialer{}
+	dialFunc := func(ctx context.Context, addr string) (net.Conn, error) {
+		if target.IP != "" {
+			_, port, err := net.SplitHostPort(addr)
+			if err != nil {
+				return nil, err
+			}
+			addr = net.JoinHostPort(target.IP, port)
+		}
+		start := time.Now()
+		conn, err := dialer.DialContext(ctx, "tcp", addr)
+		if err != nil {
+			return nil, err
+		}
+		mu.Lock()
+		result.ConnectTime = msSince(start)
+		if local, ok := conn.LocalAddr().(*net.TCPAddr); ok {
+			result.SourceIP = local.IP.String()
+		}
+		if remote, ok := conn.RemoteAddr().(*net.TCPAddr); ok {
+			result.TargetIP = remote.IP.String()
+		}
+		mu.Unlock()
+		return conn, nil
+	}
+
+	creds := insecure.NewCredentials()
+	if target.TLS {
+		creds = credentials.NewTLS(&tls.Config{
+			ServerName:         target.ServerName,
+			InsecureSkipVerify: target.Insecure,
+		})
+	}
+
+	conn, err := grpc.NewClient("passthrough:///"+target.Address,
+		grpc.WithTransportCredentials(creds),
+		grpc.WithContextDialer(dialFunc),
+	)
+	if err != nil {
+		result.Code = status.Code(err).String()
+		result.Error = fmt.Sprintf("创建连接失败: %v", err)
+		return result
+	}
+
+	start := time.Now()
+	resp, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{Service: target.Service})
+	latency := msSince(start)
+	conn.Close()
+
+	mu.Lock()
+	defer mu.Unlock()
+	result.Latency = latency
+	result.Code = status.Code(err).String()
+	if err != nil {
+		result.Status = healthpb.HealthCheckResponse_UNKNOWN.String()
+		result.Error = fmt.Sprintf("健康检查失败: %v", status.Convert(err).Message())
+		return result
+	}
+	result.Status = resp.GetStatus().String()
+	result.Serving = resp.GetStatus() == healthpb.HealthCheckResponse_SERVING
+	return result
+}
+
+func (t *GRPCHealthTask) resultInfulxDBFormat(metricName string, results []models.GRPCHealthResult) []string {
+	var lines []string
+	for _, r := range results {
+		tags := sourceTags(r.SourceIP)
+		tags = append(tags, targetTags(r.TargetIP, r.TargetNode, r.TargetHost)...)
+		tags = append(tags, fmt.Sprintf("address=%s", escapeTag(r.Address)))
+		if r.Service != "" {
+			tags = append(tags, fmt.Sprintf("service=%s", escapeTag(r.Service)))
+		}
+		tags = appendTags(tags, r.Tags)
+
+		fields := []string{
+			stringField("status", r.Status),
+			stringField("code", r.Code),
+			fmt.Sprintf("serving=%t", r.Serving),
+			fmt.Sprintf("connect_time=%f", r.ConnectTime),
+			fmt.Sprintf("latency=%f", r.Latency),
+			fmt.Sprintf("success=%t", r.Error == "" && r.Serving),
+		}
+		if r.Error != "" {
+			fields = append(fields, stringField("error", r.Error))
+		}
+
+		lines = append(lines, formatLine(metricName, tags, fields, r.Timestamp))
+	}
+	return lines
+}
+
+func (t *GRPCHealthTask) parseParams(params []interface{}) ([]models.GRPCHealthTarget, error) {
+	targets, err := decodeParams[models.GRPCHealthTarget](params)
+	if err != nil {
+		return nil, err
+	}
+
+	// 验证必要字段
+	for _, target := range targets {
+		if target.Address == "" {
+			return nil, fmt.Errorf("address field is required")
+		}
+		if _, _, err := net.SplitHostPort(target.Address); err != nil {
+			return nil, fmt.Errorf("invalid address %q: %v", target.Address, err)
+		}
+		if target.IP != "" && net.ParseIP(target.IP) == nil {
+			return nil, fmt.Errorf("invalid ip %q for target %s", target.IP, target.Address)
+		}
+	}
+	return targets, nil
+}
@@ -0,0 +1,101 @@
+package tasks
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"net_detect/internal/clock"
+	"net_detect/utils"
+)
+
+// decodeParams 将任务参数逐个转换为目标结构
+func decodeParams[T any](params []interface{}) ([]T, error) {
+	targets := make([]T, 0, len(params))
+	for _, param := range params {
+		paramMap, ok := param.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("invalid parameter type: expected map[string]interface{}, got %T", param)
+		}
+
+		paramJSON, err := json.Marshal(paramMap)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal parameter: %v", err)
+		}
+
+		var target T
+		if err := json.Unmarshal(paramJSON, &target); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal parameter to %T: %v", target, err)
+		}
+		targets = append(targets, target)
+	}
+
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("no valid targets found in parameters")
+	}
+	return targets, nil
+}
+
+// sourceTags 源端标签，与pingMesh结果保持一致
+func sourceTags(sourceIP string) []string {
+	sourceNode, _ := utils.GetNodeName()
+	sourceHost, _ := utils.GetHostName()
+	return []string{
+		fmt.Sprintf("source_ip=%s", sourceIP),
+		fmt.Sprintf("source_node=%s", sourceNode),
+		fmt.Sprintf("source_host=%s", sourceHost),
+	}
+}
+
+// targetTags 目标端标签，与pingMesh结果保持一致
+func targetTags(targetIP, targetNode, targetHost string) []string {
+	return []string{
+		fmt.Sprintf("target_ip=%s", targetIP),
+		fmt.Sprintf("target_node=%s", targetNode),
+		fmt.Sprintf("target_host=%s", targetHost),
+		fmt.Sprintf("ip_version=%s", utils.GetIPVersion(targetIP)),
+	}
+}
+
+// appendTags 追加自定义标签，标签名和值都需要转义
+func appendTags(tags []string, extra map[string]string) []string {
+	for k, v := range extra {
+		tags = append(tags, fmt.Sprintf("%s=%s", escapeTag(k), escapeTag(v)))
+	}
+	return tags
+}
+
+// formatLine 构建Influx行协议数据，本机时钟偏差超过阈值时追加clock_unsynced标记
+func formatLine(metricName string, tags, fields []string, ts time.Time) string {
+	if clock.Unsynced() {
+		fields = append(fields, "clock_unsynced=true")
+	}
+	return fmt.Sprintf("%s,%s %s %d",
+		metricName,
+		strings.Join(tags, ","),
+		strings.Join(fields, ","),
+		ts.UnixNano(),
+	)
+}
+
+var tagEscaper = strings.NewReplacer(",", `\,`, " ", `\ `, "=", `\=`)
+
+// escapeTag 转义行协议中tag值的特殊字符
+func escapeTag(v string) string {
+	return tagEscaper.Replace(v)
+}
+
+var measurementEscaper = strings.NewReplacer(",", `\,`, " ", `\ `)
+
+// escapeMeasurement 转义行协议中measurement的特殊字符
+func escapeMeasurement(v string) string {
+	return measurementEscaper.Replace(v)
+}
+
+var fieldEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+
+// stringField 构建字符串类型的field
+func stringField(key, v string) string {
+	return fmt.Sprintf(`%s="%s"`, key, fieldEscaper.Replace(v))
+}
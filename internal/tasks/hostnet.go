@@ -0,0 +1,128 @@
+package tasks
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+
+	"net_detect/internal/hostnet"
+	"net_detect/internal/models"
+	"net_detect/internal/storage"
+	"net_detect/utils"
+)
+
+// HostNetTask 本机网络计数采集任务，将/proc下的协议计数、网卡计数和conntrack使用率转换为速率，
+// 与pingMesh结果使用相同的source标签，用于判断丢包发生在源主机还是网络中。
+// 速率基于相邻两次执行的采样计算，首次执行只输出conntrack等瞬时值
+type HostNetTask struct {
+	collector *hostnet.Collector
+	storage   storage.ResultStorage
+}
+
+func NewHostNetTask(collector *hostnet.Collector, storage storage.ResultStorage) *HostNetTask {
+	return &HostNetTask{
+		collector: collector,
+		storage:   storage,
+	}
+}
+
+func (t *HostNetTask) Name() string {
+	return "hostNet"
+}
+
+func (t *HostNetTask) Execute(ctx context.Context, metricName string, params []interface{}) (Summary, error) {
+	opts, err := t.parseParams(params)
+	if err != nil {
+		return Summary{}, err
+	}
+
+	// 按metricName分别计算速率，同时存在多个hostNet任务时各自使用自己的执行间隔
+	sample, rates, err := t.collector.Collect(metricName)
+	if err != nil {
+		return Summary{}, fmt.Errorf("collect host network counters: %v", err)
+	}
+
+	return Summary{Targets: 1}, t.storage.Store(t.resultInfulxDBFormat(metricName, opts, sample, rates))
+}
+
+func (t *HostNetTask) resultInfulxDBFormat(metricName string, opts models.HostNetOptions, sample *hostnet.Sample, rates *hostnet.Rates) []string {
+	sourceIP, _ := utils.GetLocalIP("0.0.0.0")
+	tags := sourceTags(sourceIP)
+	tags = appendTags(tags, opts.Tags)
+
+	fields := []string{}
+	if estab, ok := sample.SNMP["Tcp"]["CurrEstab"]; ok {
+		fields = append(fields, fmt.Sprintf("tcp_curr_estab=%di", estab))
+	}
+	if sample.HasConntrack {
+		fields = append(fields,
+			fmt.Sprintf("conntrack_count=%di", sample.ConntrackCount),
+			fmt.Sprintf("conntrack_max=%di", sample.ConntrackMax),
+		)
+		if sample.ConntrackMax > 0 {
+			fields = append(fields, fmt.Sprintf("conntrack_fill=%f", float64(sample.ConntrackCount)/float64(sample.ConntrackMax)))
+		}
+	}
+	if rates != nil {
+		fields = append(fields, fmt.Sprintf("interval=%f", rates.Interval.Seconds()))
+		fields = append(fields, floatFields(rates.Host)...)
+	}
+
+	var lines []string
+	if len(fields) > 0 {
+		lines = append(lines, formatLine(metricName, tags, fields, sample.Time))
+	}
+	if rates == nil {
+		return lines
+	}
+
+	devices := make([]string, 0, len(rates.Devices))
+	for name := range rates.Devices {
+		if name == "lo" && !opts.IncludeLoopback {
+			continue
+		}
+		if len(opts.Devices) > 0 && !slices.Contains(opts.Devices, name) {
+			continue
+		}
+		devices = append(devices, name)
+	}
+	sort.Strings(devices)
+
+	for _, name := range devices {
+		devFields := floatFields(rates.Devices[name])
+		if len(devFields) == 0 {
+			continue
+		}
+		devTags := append(tags[:len(tags):len(tags)], fmt.Sprintf("device=%s", escapeTag(name)))
+		lines = append(lines, formatLine(metricName+"_dev", devTags, devFields, sample.Time))
+	}
+	return lines
+}
+
+// floatFields 按字段名排序输出，保证每次的行内容顺序一致
+func floatFields(values map[string]float64) []string {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	fields := make([]string, 0, len(keys))
+	for _, k := range keys {
+		fields = append(fields, fmt.Sprintf("%s=%f", k, values[k]))
+	}
+	return fields
+}
+
+// parseParams 参数可省略，多个参数时只使用第一个
+func (t *HostNetTask) parseParams(params []interface{}) (models.HostNetOptions, error) {
+	if len(params) == 0 {
+		return models.HostNetOptions{}, nil
+	}
+	opts, err := decodeParams[models.HostNetOptions](params)
+	if err != nil {
+		return models.HostNetOptions{}, err
+	}
+	return opts[0], nil
+}
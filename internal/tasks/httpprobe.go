@@ -0,0 +1,292 @@
+package tasks
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"net_detect/internal/models"
+	"net_detect/internal/storage"
+	"net_detect/utils"
+)
+
+const (
+	defaultHTTPTimeout = 10 * time.Second
+	maxHTTPBodySize    = 1 << 20  // 正则匹配最多读取1MB响应体
+	maxHTTPDiscardSize = 64 << 20 // 超出正则匹配部分最多再读取64MB，避免超大响应占满超时时间和带宽
+)
+
+// HTTPProbeTask HTTP(S)探测任务，记录各阶段耗时并校验响应
+type HTTPProbeTask struct {
+	storage storage.ResultStorage
+}
+
+func NewHTTPProbeTask(storage storage.ResultStorage) *HTTPProbeTask {
+	return &HTTPProbeTask{storage: storage}
+}
+
+func (t *HTTPProbeTask) Name() string {
+	return "httpProbe"
+}
+
+func (t *HTTPProbeTask) Execute(ctx context.Context, metricName string, params []interface{}) (Summary, error) {
+	targets, err := t.parseParams(params)
+	if err != nil {
+		return Summary{}, err
+	}
+
+	var wg sync.WaitGroup
+	results := make([]models.HTTPProbeResult, len(targets))
+
+	// 并发执行探测
+	for i, target := range targets {
+		wg.Add(1)
+		go func(index int, target models.HTTPProbeTarget) {
+			defer wg.Done()
+			results[index] = t.probe(ctx, target)
+		}(i, target)
+	}
+	wg.Wait()
+
+	return summarize(results, func(r models.HTTPProbeResult) string { return r.Error }), t.storage.Store(t.resultInfulxDBFormat(metricName, results))
+}
+
+func (t *HTTPProbeTask) probe(ctx context.Context, target models.HTTPProbeTarget) (result models.HTTPProbeResult) {
+	result = models.HTTPProbeResult{
+		TargetIP:   target.IP,
+		TargetNode: target.NodeName,
+		TargetHost: target.HostName,
+		URL:        target.URL,
+		Method:     target.Method,
+		Tags:       target.Tags,
+	}
+	defer func() { result.Timestamp = time.Now() }()
+
+	timeout := target.Timeout
+	if timeout <= 0 {
+		timeout = defaultHTTPTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	dialer := &net.Dialer{}
+	transport := &http.Transport{
+		DisableKeepAlives: true,
+		TLSClientConfig:   &tls.Config{InsecureSkipVerify: target.Insecure},
+		DialContext:       dialer.DialContext,
+	}
+	// 指定IP时直连该地址，不做DNS解析
+	if target.IP != "" {
+		transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			_, port, err := net.SplitHostPort(addr)
+			if err != nil {
+				return nil, err
+			}
+			return dialer.DialContext(ctx, network, net.JoinHostPort(target.IP, port))
+		}
+	}
+	defer transport.CloseIdleConnections()
+
+	client := &http.Client{
+		Transport: transport,
+		// 不跟随跳转，直接校验首个响应
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	// 双栈时Happy Eyeballs会对多个地址并发建连，按地址记录开始时间，只记录首个成功的连接
+	var (
+		connectMu     sync.Mutex
+		connectStarts = make(map[string]time.Time)
+		connected     bool
+	)
+	var dnsStart, tlsStart time.Time
+	trace := &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) { dnsStart = time.Now() },
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			result.DNSTime = msSince(dnsStart)
+		},
+		ConnectStart: func(_, addr string) {
+			connectMu.Lock()
+			connectStarts[addr] = time.Now()
+			connectMu.Unlock()
+		},
+		ConnectDone: func(_, addr string, err error) {
+			connectMu.Lock()
+			defer connectMu.Unlock()
+			if err == nil && !connected {
+				connected = true
+				result.ConnectTime = msSince(connectStarts[addr])
+			}
+		},
+		TLSHandshakeStart: func() { tlsStart = time.Now() },
+		TLSHandshakeDone: func(_ tls.ConnectionState, err error) {
+			if err == nil {
+				result.TLSTime = msSince(tlsStart)
+			}
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			if local, ok := info.Conn.LocalAddr().(*net.TCPAddr); ok {
+				result.SourceIP = local.IP.String()
+			}
+			if remote, ok := info.Conn.RemoteAddr().(*net.TCPAddr); ok {
+				result.TargetIP = remote.IP.String()
+			}
+		},
+	}
+
+	req, err := http.NewRequestWithContext(httptrace.WithClientTrace(ctx, trace), result.Method, target.URL, nil)
+	if err != nil {
+		result.Error = fmt.Sprintf("创建请求失败: %v", err)
+		return result
+	}
+	for k, v := range target.Headers {
+		if strings.EqualFold(k, "Host") {
+			req.Host = v
+			continue
+		}
+		req.Header.Set(k, v)
+	}
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		result.TotalTime = msSince(start)
+		result.Error = fmt.Sprintf("请求失败: %v", err)
+		return result
+	}
+	defer resp.Body.Close()
+	result.TTFB = msSince(start)
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxHTTPBodySize))
+	// 丢弃超出部分，保证total包含响应体的传输时间，超大响应只读取到上限
+	n, _ := io.Copy(io.Discard, io.LimitReader(resp.Body, maxHTTPDiscardSize))
+	result.TotalTime = msSince(start)
+	result.BodySize = int64(len(body)) + n
+	if err != nil {
+		result.Error = fmt.Sprintf("读取响应失败: %v", err)
+	}
+
+	result.StatusCode = resp.StatusCode
+	result.StatusOK = checkStatus(resp.StatusCode, target.ExpectStatus)
+	result.BodyMatch = target.BodyRegex == "" || regexp.MustCompile(target.BodyRegex).Match(body)
+	result.HeadersMatch = checkHeaders(resp.Header, target.RequireHeaders)
+	return result
+}
+
+// checkStatus 校验状态码，未指定时接受200-399
+func checkStatus(code int, expect []int) bool {
+	if len(expect) == 0 {
+		return code >= 200 && code < 400
+	}
+	for _, c := range expect {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+// checkHeaders 校验响应头，值为空时只要求存在
+func checkHeaders(header http.Header, require map[string]string) bool {
+	for name, pattern := range require {
+		values, ok := header[http.CanonicalHeaderKey(name)]
+		if !ok {
+			return false
+		}
+		if pattern == "" {
+			continue
+		}
+		re := regexp.MustCompile(pattern)
+		matched := false
+		for _, v := range values {
+			if re.MatchString(v) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+func msSince(start time.Time) float64 {
+	return float64(time.Since(start)) / float64(time.Millisecond)
+}
+
+func (t *HTTPProbeTask) resultInfulxDBFormat(metricName string, results []models.HTTPProbeResult) []string {
+	var lines []string
+	for _, r := range results {
+		tags := sourceTags(r.SourceIP)
+		tags = append(tags, targetTags(r.TargetIP, r.TargetNode, r.TargetHost)...)
+		tags = append(tags,
+			fmt.Sprintf("url=%s", escapeTag(r.URL)),
+			fmt.Sprintf("method=%s", r.Method),
+		)
+		tags = appendTags(tags, r.Tags)
+
+		success := r.Error == "" && r.StatusOK && r.BodyMatch && r.HeadersMatch
+		fields := []string{
+			fmt.Sprintf("dns_time=%f", r.DNSTime),
+			fmt.Sprintf("connect_time=%f", r.ConnectTime),
+			fmt.Sprintf("tls_time=%f", r.TLSTime),
+			fmt.Sprintf("ttfb=%f", r.TTFB),
+			fmt.Sprintf("total_time=%f", r.TotalTime),
+			fmt.Sprintf("status_code=%di", r.StatusCode),
+			fmt.Sprintf("body_size=%di", r.BodySize),
+			fmt.Sprintf("status_ok=%t", r.StatusOK),
+			fmt.Sprintf("body_match=%t", r.BodyMatch),
+			fmt.Sprintf("headers_match=%t", r.HeadersMatch),
+			fmt.Sprintf("success=%t", success),
+		}
+		if r.Error != "" {
+			fields = append(fields, stringField("error", r.Error))
+		}
+
+		lines = append(lines, formatLine(metricName, tags, fields, r.Timestamp))
+	}
+	return lines
+}
+
+func (t *HTTPProbeTask) parseParams(params []interface{}) ([]models.HTTPProbeTarget, error) {
+	targets, err := decodeParams[models.HTTPProbeTarget](params)
+	if err != nil {
+		return nil, err
+	}
+
+	// 验证必要字段，提前检查正则避免探测时panic
+	for i := range targets {
+		target := &targets[i]
+		u, err := url.Parse(target.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			return nil, fmt.Errorf("invalid url: %q", target.URL)
+		}
+		if target.Method == "" {
+			target.Method = http.MethodGet
+		}
+		if target.IP != "" && utils.GetIPVersion(target.IP) == "Unknown" {
+			return nil, fmt.Errorf("invalid ip %q for url %s", target.IP, target.URL)
+		}
+		if _, err := regexp.Compile(target.BodyRegex); err != nil {
+			return nil, fmt.Errorf("invalid bodyRegex for url %s: %v", target.URL, err)
+		}
+		for name, pattern := range target.RequireHeaders {
+			if _, err := regexp.Compile(pattern); err != nil {
+				return nil, fmt.Errorf("invalid requireHeaders pattern for %s: %v", name, err)
+			}
+		}
+	}
+	return targets, nil
+}
@@ -0,0 +1,177 @@
+package tasks
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"net_detect/internal/clock"
+	"net_detect/internal/models"
+	"net_detect/internal/storage"
+)
+
+const defaultNTPTimeout = 2 * time.Second
+
+// NTPTask NTP时钟偏差探测任务，输出每个服务器的结果以及本节点的时钟健康序列，
+// 并更新本机时钟状态，偏差超过阈值时其它任务的结果会带上clock_unsynced标记
+type NTPTask struct {
+	storage storage.ResultStorage
+}
+
+func NewNTPTask(storage storage.ResultStorage) *NTPTask {
+	return &NTPTask{storage: storage}
+}
+
+func (t *NTPTask) Name() string {
+	return "ntp"
+}
+
+func (t *NTPTask) Execute(ctx context.Context, metricName string, params []interface{}) (Summary, error) {
+	targets, err := t.parseParams(params)
+	if err != nil {
+		return Summary{}, err
+	}
+
+	var wg sync.WaitGroup
+	results := make([]models.NTPResult, len(targets))
+
+	// 并发执行探测
+	for i, target := range targets {
+		wg.Add(1)
+		go func(index int, target models.NTPTarget) {
+			defer wg.Done()
+			results[index] = t.query(ctx, target)
+		}(i, target)
+	}
+	wg.Wait()
+
+	// 以时延最小的服务器作为本机时钟偏差
+	best := -1
+	for i, r := range results {
+		if r.Error == "" && r.Leap != 3 && (best < 0 || r.Delay < results[best].Delay) {
+			best = i
+		}
+	}
+	if best >= 0 {
+		clock.Update(time.Duration(results[best].Offset * float64(time.Millisecond)))
+	}
+
+	lines := t.resultInfulxDBFormat(metricName, results)
+	lines = append(lines, t.healthLine(metricName, results, best))
+	return summarize(results, func(r models.NTPResult) string { return r.Error }), t.storage.Store(lines)
+}
+
+func (t *NTPTask) query(ctx context.Context, target models.NTPTarget) models.NTPResult {
+	result := models.NTPResult{
+		ServerIP:   target.Server,
+		TargetNode: target.NodeName,
+		TargetHost: target.HostName,
+		Tags:       target.Tags,
+	}
+
+	timeout := target.Timeout
+	if timeout <= 0 {
+		timeout = defaultNTPTimeout
+	}
+	resp, err := clock.QueryNTP(ctx, target.Server, timeout)
+	result.Timestamp = time.Now()
+	if resp.ServerIP != "" {
+		result.ServerIP = resp.ServerIP
+	}
+	result.SourceIP = resp.LocalIP
+	if err != nil {
+		result.Error = fmt.Sprintf("查询NTP失败: %v", err)
+		return result
+	}
+
+	result.Offset = float64(resp.Offset) / float64(time.Millisecond)
+	result.Delay = float64(resp.Delay) / float64(time.Millisecond)
+	result.Stratum = resp.Stratum
+	result.Leap = resp.Leap
+	result.RefID = resp.RefID
+	return result
+}
+
+func (t *NTPTask) resultInfulxDBFormat(metricName string, results []models.NTPResult) []string {
+	var lines []string
+	for _, r := range results {
+		tags := sourceTags(r.SourceIP)
+		tags = append(tags, targetTags(r.ServerIP, r.TargetNode, r.TargetHost)...)
+		tags = appendTags(tags, r.Tags)
+
+		var fields []string
+		if r.Error != "" {
+			fields = []string{
+				"success=false",
+				stringField("error", r.Error),
+			}
+		} else {
+			fields = []string{
+				"success=true",
+				fmt.Sprintf("offset=%f", r.Offset),
+				fmt.Sprintf("delay=%f", r.Delay),
+				fmt.Sprintf("stratum=%di", r.Stratum),
+				fmt.Sprintf("leap=%di", r.Leap),
+				stringField("ref_id", r.RefID),
+			}
+		}
+
+		lines = append(lines, formatLine(metricName, tags, fields, r.Timestamp))
+	}
+	return lines
+}
+
+// healthLine 本节点的时钟健康序列，写入 <metricName>_health
+func (t *NTPTask) healthLine(metricName string, results []models.NTPResult, best int) string {
+	ok := 0
+	for _, r := range results {
+		if r.Error == "" {
+			ok++
+		}
+	}
+
+	fields := []string{
+		fmt.Sprintf("servers=%di", len(results)),
+		fmt.Sprintf("servers_ok=%di", ok),
+		fmt.Sprintf("synced=%t", clock.Synced()),
+	}
+	if best >= 0 {
+		fields = append(fields,
+			fmt.Sprintf("offset=%f", results[best].Offset),
+			fmt.Sprintf("delay=%f", results[best].Delay),
+			fmt.Sprintf("stratum=%di", results[best].Stratum),
+			fmt.Sprintf("leap=%di", results[best].Leap),
+			stringField("server", results[best].ServerIP),
+		)
+	}
+
+	sourceIP := ""
+	if best >= 0 {
+		sourceIP = results[best].SourceIP
+	}
+	return formatLine(metricName+"_health", sourceTags(sourceIP), fields, time.Now())
+}
+
+func (t *NTPTask) parseParams(params []interface{}) ([]models.NTPTarget, error) {
+	targets, err := decodeParams[models.NTPTarget](params)
+	if err != nil {
+		return nil, err
+	}
+
+	// 验证必要字段
+	for _, target := range targets {
+		if target.Server == "" {
+			return nil, fmt.Errorf("server field is required")
+		}
+		host := target.Server
+		if h, _, err := net.SplitHostPort(target.Server); err == nil {
+			host = h
+		}
+		if host == "" {
+			return nil, fmt.Errorf("invalid server %q", target.Server)
+		}
+	}
+	return targets, nil
+}
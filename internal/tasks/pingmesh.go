@@ -1,27 +1,39 @@
 package tasks
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net_detect/internal/models"
 	"net_detect/internal/ping"
 	"net_detect/internal/storage"
 	"net_detect/utils"
-	"strings"
+	"strconv"
 	"sync"
+	"time"
 )
 
-// PingMeshTask pingMesh任务实现
+const defaultPingConcurrency = 256
+
+// PingMeshTask pingMesh任务实现。同时探测的目标数不超过maxConcurrency，
+// 各目标的启动时间均匀分布在spreadWindow内，避免大量目标同时发包
 type PingMeshTask struct {
-	pinger     ping.Pinger
-	storage    storage.ResultStorage
-	metricName string
+	pinger         ping.Pinger
+	storage        storage.ResultStorage
+	metricName     string
+	maxConcurrency int
+	spreadWindow   time.Duration
 }
 
-func NewPingMeshTask(pinger ping.Pinger, storage storage.ResultStorage) *PingMeshTask {
+func NewPingMeshTask(pinger ping.Pinger, storage storage.ResultStorage, maxConcurrency int, spreadWindow time.Duration) *PingMeshTask {
+	if maxConcurrency <= 0 {
+		maxConcurrency = defaultPingConcurrency
+	}
 	return &PingMeshTask{
-		pinger:  pinger,
-		storage: storage,
+		pinger:         pinger,
+		storage:        storage,
+		maxConcurrency: maxConcurrency,
+		spreadWindow:   spreadWindow,
 	}
 }
 
@@ -29,26 +41,55 @@ func (t *PingMeshTask) Name() string {
 	return "pingMesh"
 }
 
-func (t *PingMeshTask) Execute(metricName string, params []interface{}) error {
+func (t *PingMeshTask) Execute(ctx context.Context, metricName string, params []interface{}) (Summary, error) {
 	targets, err := t.parseParams(params)
 	if err != nil {
-		return err
+		return Summary{}, err
 	}
 
 	var wg sync.WaitGroup
 	results := make([]models.PingResult, len(targets))
 
-	// 并发执行ping
-	for i, target := range targets {
+	workers := t.maxConcurrency
+	if workers > len(targets) {
+		workers = len(targets)
+	}
+	jobs := make(chan int)
+	for w := 0; w < workers; w++ {
 		wg.Add(1)
-		go func(index int, target models.PingTarget) {
+		go func() {
 			defer wg.Done()
-			results[index] = t.pinger.Ping(target)
-		}(i, target)
+			for index := range jobs {
+				results[index] = t.pinger.Ping(ctx, targets[index])
+			}
+		}()
+	}
+
+	// 按启动时间依次分发目标，worker都在忙时顺延；ctx结束后不再分发，只保存已开始探测的目标
+	step := t.spreadWindow / time.Duration(len(targets))
+	start := time.Now()
+	dispatched := 0
+dispatch:
+	for i := range targets {
+		timer := time.NewTimer(time.Until(start.Add(step * time.Duration(i))))
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			break dispatch
+		}
+		select {
+		case jobs <- i:
+			dispatched++
+		case <-ctx.Done():
+			break dispatch
+		}
 	}
+	close(jobs)
 	wg.Wait()
+	results = results[:dispatched]
 
-	return t.storage.Store(t.resultInfulxDBFormat(metricName, results))
+	return summarize(results, func(r models.PingResult) string { return r.Error }), t.storage.Store(t.resultInfulxDBFormat(metricName, results))
 }
 
 func (t *PingMeshTask) resultInfulxDBFormat(metricName string, results []models.PingResult) []string {
@@ -71,6 +112,9 @@ func (t *PingMeshTask) resultInfulxDBFormat(metricName string, results []models.
 		for k, v := range r.Tags {
 			tags = append(tags, fmt.Sprintf("%s=%s", k, v))
 		}
+		for k, v := range r.ProbeTags {
+			tags = append(tags, fmt.Sprintf("%s=%s", k, v))
+		}
 
 		// 构建fields
 		fields := []string{
@@ -81,19 +125,23 @@ func (t *PingMeshTask) resultInfulxDBFormat(metricName string, results []models.
 			fmt.Sprintf("rtt_max=%f", r.MaxRtt),
 			fmt.Sprintf("rtt_avg=%f", r.AvgRtt),
 			fmt.Sprintf("rtt_std_dev=%f", r.StdDevRtt),
+			fmt.Sprintf("rtt_p50=%f", r.P50Rtt),
+			fmt.Sprintf("rtt_p90=%f", r.P90Rtt),
+			fmt.Sprintf("rtt_p99=%f", r.P99Rtt),
+			fmt.Sprintf("jitter=%f", r.Jitter),
+		}
+		// 每个序号的RTT输出为单独的数值field，丢失的序号不输出
+		for seq, rtt := range r.Rtts {
+			if rtt >= 0 {
+				fields = append(fields, fmt.Sprintf("rtt_seq_%d=%s", seq, strconv.FormatFloat(rtt, 'f', 3, 64)))
+			}
 		}
 		if r.Error != "" {
 			fields = append(fields, fmt.Sprintf("error=%v", r.Error))
 		}
 
 		// 构建完整的行
-		line := fmt.Sprintf("%s,%s %s %d",
-			metricName,
-			strings.Join(tags, ","),
-			strings.Join(fields, ","),
-			r.Timestamp.UnixNano(),
-		)
-		lines = append(lines, line)
+		lines = append(lines, formatLine(metricName, tags, fields, r.Timestamp))
 	}
 	return lines
 }
@@ -122,6 +170,21 @@ func (t *PingMeshTask) parseParams(params []interface{}) ([]models.PingTarget, e
 		if target.IP == "" {
 			return nil, fmt.Errorf("IP field is required")
 		}
+		if target.Count < 0 || target.Interval < 0 || target.Timeout < 0 || target.Size < 0 {
+			return nil, fmt.Errorf("count, interval, timeout and size must not be negative for %s", target.IP)
+		}
+		if target.Count > ping.MaxEchoCount {
+			return nil, fmt.Errorf("count must not exceed %d for %s", ping.MaxEchoCount, target.IP)
+		}
+		if target.Size != 0 && target.Size < ping.MinEchoSize {
+			return nil, fmt.Errorf("size must be 0 or at least %d for %s", ping.MinEchoSize, target.IP)
+		}
+		if target.TTL < 0 || target.TTL > 255 {
+			return nil, fmt.Errorf("invalid ttl %d for %s", target.TTL, target.IP)
+		}
+		if target.TOS < 0 || target.TOS > 255 || target.DSCP < 0 || target.DSCP > 63 {
+			return nil, fmt.Errorf("invalid tos/dscp for %s", target.IP)
+		}
 
 		targets = append(targets, target)
 	}
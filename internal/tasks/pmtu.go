@@ -0,0 +1,110 @@
+package tasks
+
+import (
+	"context"
+	"fmt"
+	"net_detect/internal/models"
+	"net_detect/internal/ping"
+	"net_detect/internal/storage"
+	"sync"
+)
+
+// PMTUTask 路径MTU探测任务，记录每个源/目标对的MTU及与上次结果的变化
+type PMTUTask struct {
+	prober  ping.MTUProber
+	storage storage.ResultStorage
+
+	mu   sync.Mutex
+	last map[string]int // metricName/目标IP -> 上次探测到的MTU
+}
+
+func NewPMTUTask(prober ping.MTUProber, storage storage.ResultStorage) *PMTUTask {
+	return &PMTUTask{
+		prober:  prober,
+		storage: storage,
+		last:    make(map[string]int),
+	}
+}
+
+func (t *PMTUTask) Name() string {
+	return "pmtu"
+}
+
+func (t *PMTUTask) Execute(ctx context.Context, metricName string, params []interface{}) (Summary, error) {
+	targets, err := t.parseParams(params)
+	if err != nil {
+		return Summary{}, err
+	}
+
+	var wg sync.WaitGroup
+	results := make([]models.MTUResult, len(targets))
+
+	// 并发执行探测
+	for i, target := range targets {
+		wg.Add(1)
+		go func(index int, target models.MTUTarget) {
+			defer wg.Done()
+			results[index] = t.prober.Discover(ctx, target)
+		}(i, target)
+	}
+	wg.Wait()
+
+	return summarize(results, func(r models.MTUResult) string { return r.Error }), t.storage.Store(t.resultInfulxDBFormat(metricName, results))
+}
+
+func (t *PMTUTask) resultInfulxDBFormat(metricName string, results []models.MTUResult) []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var lines []string
+	for _, r := range results {
+		tags := sourceTags(r.SourceIP)
+		tags = append(tags, targetTags(r.TargetIP, r.TargetNode, r.TargetHost)...)
+		tags = appendTags(tags, r.Tags)
+
+		fields := []string{
+			fmt.Sprintf("probes=%di", r.Probes),
+		}
+		if r.Error != "" {
+			fields = append(fields, stringField("error", r.Error))
+			lines = append(lines, formatLine(metricName, tags, fields, r.Timestamp))
+			continue
+		}
+
+		// 与上次结果比较，首次探测不认为发生变化
+		key := fmt.Sprintf("%s/%s", metricName, r.TargetIP)
+		prev, seen := t.last[key]
+		t.last[key] = r.MTU
+		fields = append(fields,
+			fmt.Sprintf("mtu=%di", r.MTU),
+			fmt.Sprintf("mtu_changed=%t", seen && prev != r.MTU),
+		)
+		if seen {
+			fields = append(fields,
+				fmt.Sprintf("mtu_prev=%di", prev),
+				fmt.Sprintf("mtu_delta=%di", r.MTU-prev),
+			)
+		}
+
+		lines = append(lines, formatLine(metricName, tags, fields, r.Timestamp))
+	}
+	return lines
+}
+
+func (t *PMTUTask) parseParams(params []interface{}) ([]models.MTUTarget, error) {
+	targets, err := decodeParams[models.MTUTarget](params)
+	if err != nil {
+		return nil, err
+	}
+
+	// 验证必要字段
+	for _, target := range targets {
+		if target.IP == "" {
+			return nil, fmt.Errorf("IP field is required")
+		}
+		if target.MaxMTU > 65535 {
+			return nil, fmt.Errorf("invalid maxMtu %d for target %s", target.MaxMTU, target.IP)
+		}
+	}
+	return targets, nil
+}
@@ -0,0 +1,326 @@
+package tasks
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"net_detect/internal/models"
+	"net_detect/internal/storage"
+
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/http3"
+	"github.com/quic-go/quic-go/logging"
+)
+
+const (
+	defaultQUICTimeout = 10 * time.Second
+	quicSessionCache   = 256
+)
+
+// QUICProbeTask QUIC握手及HTTP/3请求探测任务，会话票据在任务内缓存，供下次探测尝试0-RTT
+type QUICProbeTask struct {
+	storage  storage.ResultStorage
+	sessions tls.ClientSessionCache
+}
+
+func NewQUICProbeTask(storage storage.ResultStorage) *QUICProbeTask {
+	return &QUICProbeTask{
+		storage:  storage,
+		sessions: tls.NewLRUClientSessionCache(quicSessionCache),
+	}
+}
+
+func (t *QUICProbeTask) Name() string {
+	return "quicProbe"
+}
+
+func (t *QUICProbeTask) Execute(ctx context.Context, metricName string, params []interface{}) (Summary, error) {
+	targets, err := t.parseParams(params)
+	if err != nil {
+		return Summary{}, err
+	}
+
+	var wg sync.WaitGroup
+	results := make([]models.QUICProbeResult, len(targets))
+
+	// 并发执行探测
+	for i, target := range targets {
+		wg.Add(1)
+		go func(index int, target models.QUICProbeTarget) {
+			defer wg.Done()
+			results[index] = t.probe(ctx, target)
+		}(i, target)
+	}
+	wg.Wait()
+
+	return summarize(results, func(r models.QUICProbeResult) string { return r.Error }), t.storage.Store(t.resultInfulxDBFormat(metricName, results))
+}
+
+func (t *QUICProbeTask) probe(ctx context.Context, target models.QUICProbeTarget) (result models.QUICProbeResult) {
+	result = models.QUICProbeResult{
+		TargetIP:   target.IP,
+		TargetNode: target.NodeName,
+		TargetHost: target.HostName,
+		Address:    target.Address,
+		Tags:       target.Tags,
+	}
+	defer func() { result.Timestamp = time.Now() }()
+
+	timeout := target.Timeout
+	if timeout <= 0 {
+		timeout = defaultQUICTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	host, port := splitQUICAddress(target.Address)
+	dialHost := host
+	if target.IP != "" {
+		dialHost = target.IP
+	}
+	raddr, err := net.ResolveUDPAddr("udp", net.JoinHostPort(dialHost, port))
+	if err != nil {
+		result.ErrorClass = models.QUICErrorOther
+		result.Error = fmt.Sprintf("解析地址失败: %v", err)
+		return result
+	}
+	result.TargetIP = raddr.IP.String()
+	// 监听地址为通配地址，按路由取实际使用的源地址
+	result.SourceIP, _ = localIPFor(raddr)
+
+	udpConn, err := net.ListenUDP("udp", nil)
+	if err != nil {
+		result.ErrorClass = models.QUICErrorOther
+		result.Error = fmt.Sprintf("创建socket失败: %v", err)
+		return result
+	}
+	transport := &quic.Transport{Conn: udpConn}
+	defer transport.Close()
+
+	serverName := target.ServerName
+	if serverName == "" {
+		serverName = host
+	}
+	alpn := target.ALPN
+	if len(alpn) == 0 {
+		alpn = []string{http3.NextProtoH3}
+	}
+	tlsConf := &tls.Config{
+		ServerName:         serverName,
+		NextProtos:         alpn,
+		InsecureSkipVerify: target.Insecure,
+		ClientSessionCache: t.sessions,
+	}
+
+	// 记录是否收到过对端的任何报文，用于区分UDP被过滤和握手失败
+	var gotPacket atomic.Bool
+	quicConf := &quic.Config{
+		HandshakeIdleTimeout: timeout,
+		Tracer: func(context.Context, logging.Perspective, quic.ConnectionID) *logging.ConnectionTracer {
+			mark := func() { gotPacket.Store(true) }
+			return &logging.ConnectionTracer{
+				ReceivedVersionNegotiationPacket: func(_, _ logging.ArbitraryLenConnectionID, _ []logging.Version) { mark() },
+				ReceivedRetry:                    func(*logging.Header) { mark() },
+				ReceivedLongHeaderPacket:         func(*logging.ExtendedHeader, logging.ByteCount, logging.ECN, []logging.Frame) { mark() },
+				ReceivedShortHeaderPacket:        func(*logging.ShortHeader, logging.ByteCount, logging.ECN, []logging.Frame) { mark() },
+			}
+		},
+	}
+
+	start := time.Now()
+	conn, err := transport.DialEarly(ctx, raddr, tlsConf, quicConf)
+	if err != nil {
+		result.TotalTime = msSince(start)
+		result.ErrorClass = classifyQUICError(err, gotPacket.Load())
+		result.Error = fmt.Sprintf("QUIC握手失败: %v", err)
+		return result
+	}
+	defer conn.CloseWithError(0, "")
+
+	// 0-RTT时DialEarly在握手完成前返回，握手失败通过连接的context反映
+	handshake := make(chan error, 1)
+	go func() {
+		select {
+		case <-conn.HandshakeComplete():
+			result.HandshakeTime = msSince(start)
+			handshake <- nil
+		case <-conn.Context().Done():
+			handshake <- context.Cause(conn.Context())
+		case <-ctx.Done():
+			handshake <- ctx.Err()
+		}
+	}()
+
+	// 开启0-RTT时请求随握手一起发出，否则等待握手完成后再请求
+	if target.HTTP3 {
+		method := http.MethodGet
+		if target.ZeroRTT {
+			method = http3.MethodGet0RTT
+		}
+		t.request(ctx, conn, method, host, port, target.Path, start, &result)
+	}
+
+	// 握手失败时以握手错误为准
+	if err := <-handshake; err != nil {
+		result.TotalTime = msSince(start)
+		result.ErrorClass = classifyQUICError(err, gotPacket.Load())
+		result.Error = fmt.Sprintf("QUIC握手失败: %v", err)
+		return result
+	}
+
+	state := conn.ConnectionState()
+	result.Version = state.Version.String()
+	result.ALPN = state.TLS.NegotiatedProtocol
+	result.Resumed = state.TLS.DidResume
+	result.Used0RTT = state.Used0RTT
+	if !target.HTTP3 {
+		result.TotalTime = msSince(start)
+	}
+	return result
+}
+
+// request 在已建立的QUIC连接上发送HTTP/3请求
+func (t *QUICProbeTask) request(ctx context.Context, conn quic.EarlyConnection, method, host, port, path string, start time.Time, result *models.QUICProbeResult) {
+	if path == "" {
+		path = "/"
+	}
+	authority := host
+	if port != "443" {
+		authority = net.JoinHostPort(host, port)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, "https://"+authority+path, nil)
+	if err != nil {
+		result.ErrorClass = models.QUICErrorHTTP
+		result.Error = fmt.Sprintf("创建请求失败: %v", err)
+		return
+	}
+
+	client := (&http3.Transport{}).NewClientConn(conn)
+	reqStart := time.Now()
+	resp, err := client.RoundTrip(req)
+	if err != nil {
+		result.RequestTime = msSince(reqStart)
+		result.TotalTime = msSince(start)
+		result.ErrorClass = models.QUICErrorHTTP
+		if errors.Is(err, context.DeadlineExceeded) {
+			result.ErrorClass = models.QUICErrorTimeout
+		}
+		result.Error = fmt.Sprintf("请求失败: %v", err)
+		return
+	}
+	defer resp.Body.Close()
+	result.RequestTime = msSince(reqStart)
+	result.StatusCode = resp.StatusCode
+
+	if _, err := io.Copy(io.Discard, resp.Body); err != nil {
+		result.ErrorClass = models.QUICErrorHTTP
+		result.Error = fmt.Sprintf("读取响应失败: %v", err)
+	}
+	result.TotalTime = msSince(start)
+}
+
+// classifyQUICError 错误分类，未收到任何应答的超时和ICMP不可达归为udp_blocked
+func classifyQUICError(err error, gotPacket bool) string {
+	var (
+		netErr     net.Error
+		versionErr *quic.VersionNegotiationError
+		transErr   *quic.TransportError
+	)
+	switch {
+	case errors.Is(err, syscall.ECONNREFUSED), errors.Is(err, syscall.EHOSTUNREACH),
+		errors.Is(err, syscall.ENETUNREACH), errors.Is(err, syscall.EACCES):
+		return models.QUICErrorUDPBlocked
+	case errors.As(err, &versionErr):
+		return models.QUICErrorVersion
+	case errors.As(err, &transErr) && transErr.ErrorCode.IsCryptoError():
+		return models.QUICErrorTLS
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		if !gotPacket {
+			return models.QUICErrorUDPBlocked
+		}
+		return models.QUICErrorTimeout
+	default:
+		return models.QUICErrorOther
+	}
+}
+
+// splitQUICAddress 拆分host和端口，省略端口时为443
+func splitQUICAddress(address string) (string, string) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return address, "443"
+	}
+	return host, port
+}
+
+// localIPFor 获取发往目标地址时使用的本地IP
+func localIPFor(raddr *net.UDPAddr) (string, error) {
+	conn, err := net.DialUDP("udp", nil, raddr)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	return conn.LocalAddr().(*net.UDPAddr).IP.String(), nil
+}
+
+func (t *QUICProbeTask) resultInfulxDBFormat(metricName string, results []models.QUICProbeResult) []string {
+	var lines []string
+	for _, r := range results {
+		tags := sourceTags(r.SourceIP)
+		tags = append(tags, targetTags(r.TargetIP, r.TargetNode, r.TargetHost)...)
+		tags = append(tags, fmt.Sprintf("address=%s", escapeTag(r.Address)))
+		if r.ErrorClass != "" {
+			tags = append(tags, fmt.Sprintf("error_class=%s", r.ErrorClass))
+		}
+		tags = appendTags(tags, r.Tags)
+
+		fields := []string{
+			fmt.Sprintf("handshake_time=%f", r.HandshakeTime),
+			fmt.Sprintf("request_time=%f", r.RequestTime),
+			fmt.Sprintf("total_time=%f", r.TotalTime),
+			stringField("version", r.Version),
+			stringField("alpn", r.ALPN),
+			fmt.Sprintf("resumed=%t", r.Resumed),
+			fmt.Sprintf("used_0rtt=%t", r.Used0RTT),
+			fmt.Sprintf("status_code=%di", r.StatusCode),
+			fmt.Sprintf("success=%t", r.Error == ""),
+		}
+		if r.Error != "" {
+			fields = append(fields, stringField("error", r.Error))
+		}
+
+		lines = append(lines, formatLine(metricName, tags, fields, r.Timestamp))
+	}
+	return lines
+}
+
+func (t *QUICProbeTask) parseParams(params []interface{}) ([]models.QUICProbeTarget, error) {
+	targets, err := decodeParams[models.QUICProbeTarget](params)
+	if err != nil {
+		return nil, err
+	}
+
+	// 验证必要字段
+	for _, target := range targets {
+		if target.Address == "" {
+			return nil, fmt.Errorf("address field is required")
+		}
+		if target.IP != "" && net.ParseIP(target.IP) == nil {
+			return nil, fmt.Errorf("invalid ip %q for target %s", target.IP, target.Address)
+		}
+		if target.Path != "" && target.Path[0] != '/' {
+			return nil, fmt.Errorf("invalid path %q for target %s", target.Path, target.Address)
+		}
+	}
+	return targets, nil
+}
@@ -0,0 +1,104 @@
+package tasks
+
+import (
+	"context"
+	"fmt"
+	"net_detect/internal/models"
+	"net_detect/internal/ping"
+	"net_detect/internal/storage"
+	"sync"
+)
+
+// STAMPTask STAMP(RFC 8762)会话发送端任务，分别输出正向和反向时延
+type STAMPTask struct {
+	sender  ping.STAMPSender
+	storage storage.ResultStorage
+}
+
+func NewSTAMPTask(sender ping.STAMPSender, storage storage.ResultStorage) *STAMPTask {
+	return &STAMPTask{
+		sender:  sender,
+		storage: storage,
+	}
+}
+
+func (t *STAMPTask) Name() string {
+	return "stamp"
+}
+
+func (t *STAMPTask) Execute(ctx context.Context, metricName string, params []interface{}) (Summary, error) {
+	targets, err := t.parseParams(params)
+	if err != nil {
+		return Summary{}, err
+	}
+
+	var wg sync.WaitGroup
+	results := make([]models.STAMPResult, len(targets))
+
+	// 并发执行探测
+	for i, target := range targets {
+		wg.Add(1)
+		go func(index int, target models.STAMPTarget) {
+			defer wg.Done()
+			results[index] = t.sender.Send(ctx, target)
+		}(i, target)
+	}
+	wg.Wait()
+
+	return summarize(results, func(r models.STAMPResult) string { return r.Error }), t.storage.Store(t.resultInfulxDBFormat(metricName, results))
+}
+
+func (t *STAMPTask) resultInfulxDBFormat(metricName string, results []models.STAMPResult) []string {
+	var lines []string
+	for _, r := range results {
+		tags := sourceTags(r.SourceIP)
+		tags = append(tags, targetTags(r.TargetIP, r.TargetNode, r.TargetHost)...)
+		tags = appendTags(tags, r.Tags)
+
+		fields := []string{
+			fmt.Sprintf("packets_sent=%di", r.PacketsSent),
+			fmt.Sprintf("packets_recv=%di", r.PacketsRecv),
+			fmt.Sprintf("packets_loss=%di", r.PacketsSent-r.PacketsRecv),
+			fmt.Sprintf("fwd_loss=%di", r.FwdLoss),
+			fmt.Sprintf("rev_loss=%di", r.RevLoss),
+			fmt.Sprintf("unknown_loss=%di", r.UnknownLoss),
+			fmt.Sprintf("fwd_delay_min=%f", r.FwdDelayMin),
+			fmt.Sprintf("fwd_delay_max=%f", r.FwdDelayMax),
+			fmt.Sprintf("fwd_delay_avg=%f", r.FwdDelayAvg),
+			fmt.Sprintf("fwd_jitter=%f", r.FwdJitter),
+			fmt.Sprintf("bwd_delay_min=%f", r.BwdDelayMin),
+			fmt.Sprintf("bwd_delay_max=%f", r.BwdDelayMax),
+			fmt.Sprintf("bwd_delay_avg=%f", r.BwdDelayAvg),
+			fmt.Sprintf("bwd_jitter=%f", r.BwdJitter),
+			fmt.Sprintf("rtt_min=%f", r.MinRtt),
+			fmt.Sprintf("rtt_max=%f", r.MaxRtt),
+			fmt.Sprintf("rtt_avg=%f", r.AvgRtt),
+			fmt.Sprintf("sender_ttl=%di", r.SenderTTL),
+			fmt.Sprintf("reflector_sync=%t", r.ReflectorSync),
+		}
+		if r.Error != "" {
+			fields = append(fields, stringField("error", r.Error))
+		}
+
+		lines = append(lines, formatLine(metricName, tags, fields, r.Timestamp))
+	}
+	return lines
+}
+
+func (t *STAMPTask) parseParams(params []interface{}) ([]models.STAMPTarget, error) {
+	targets, err := decodeParams[models.STAMPTarget](params)
+	if err != nil {
+		return nil, err
+	}
+
+	// 验证必要字段
+	for _, target := range targets {
+		if target.IP == "" {
+			return nil, fmt.Errorf("IP field is required")
+		}
+		if target.Port < 0 || target.Port > 65535 {
+			return nil, fmt.Errorf("invalid port %d for target %s", target.Port, target.IP)
+		}
+	}
+	return targets, nil
+}
@@ -1,7 +1,30 @@
 package tasks
 
-// Task 定义任务接口
+import "context"
+
+// Task 定义任务接口，返回本次执行的统计。ctx结束时任务应尽快停止探测，并存储已得到的结果
 type Task interface {
 	Name() string
-	Execute(metricName string, params []any) error
+	Execute(ctx context.Context, metricName string, params []any) (Summary, error)
+}
+
+// Summary 单次执行的统计，Errors为探测失败的目标数，FirstError为其中一个目标的失败原因
+type Summary struct {
+	Targets    int
+	Errors     int
+	FirstError string
+}
+
+// summarize 按结果的错误信息统计失败目标数
+func summarize[T any](results []T, errOf func(T) string) Summary {
+	s := Summary{Targets: len(results)}
+	for _, r := range results {
+		if e := errOf(r); e != "" {
+			s.Errors++
+			if s.FirstError == "" {
+				s.FirstError = e
+			}
+		}
+	}
+	return s
 }
@@ -0,0 +1,97 @@
+package tasks
+
+import (
+	"context"
+	"fmt"
+	"net_detect/internal/models"
+	"net_detect/internal/ping"
+	"net_detect/internal/storage"
+	"sync"
+)
+
+// TCPPingTask TCP握手时延探测任务，用于丢弃ICMP的目标
+type TCPPingTask struct {
+	pinger  ping.TCPPinger
+	storage storage.ResultStorage
+}
+
+func NewTCPPingTask(pinger ping.TCPPinger, storage storage.ResultStorage) *TCPPingTask {
+	return &TCPPingTask{
+		pinger:  pinger,
+		storage: storage,
+	}
+}
+
+func (t *TCPPingTask) Name() string {
+	return "tcpPing"
+}
+
+func (t *TCPPingTask) Execute(ctx context.Context, metricName string, params []interface{}) (Summary, error) {
+	targets, err := t.parseParams(params)
+	if err != nil {
+		return Summary{}, err
+	}
+
+	var wg sync.WaitGroup
+	results := make([]models.TCPPingResult, len(targets))
+
+	// 并发执行探测
+	for i, target := range targets {
+		wg.Add(1)
+		go func(index int, target models.TCPPingTarget) {
+			defer wg.Done()
+			results[index] = t.pinger.Ping(ctx, target)
+		}(i, target)
+	}
+	wg.Wait()
+
+	return summarize(results, func(r models.TCPPingResult) string { return r.Error }), t.storage.Store(t.resultInfulxDBFormat(metricName, results))
+}
+
+func (t *TCPPingTask) resultInfulxDBFormat(metricName string, results []models.TCPPingResult) []string {
+	var lines []string
+	for _, r := range results {
+		tags := sourceTags(r.SourceIP)
+		tags = append(tags, targetTags(r.TargetIP, r.TargetNode, r.TargetHost)...)
+		tags = append(tags, fmt.Sprintf("target_port=%d", r.TargetPort))
+		tags = appendTags(tags, r.Tags)
+
+		fields := []string{
+			fmt.Sprintf("packets_sent=%di", r.ProbesSent),
+			fmt.Sprintf("packets_recv=%di", r.ProbesSuccess),
+			fmt.Sprintf("packets_loss=%di", r.ProbesSent-r.ProbesSuccess),
+			fmt.Sprintf("refused=%di", r.Refused),
+			fmt.Sprintf("reset=%di", r.Reset),
+			fmt.Sprintf("timeout=%di", r.Timeout),
+			fmt.Sprintf("other_error=%di", r.OtherErrors),
+			fmt.Sprintf("rtt_min=%f", r.MinRtt),
+			fmt.Sprintf("rtt_max=%f", r.MaxRtt),
+			fmt.Sprintf("rtt_avg=%f", r.AvgRtt),
+			fmt.Sprintf("rtt_std_dev=%f", r.StdDevRtt),
+		}
+		if r.Error != "" {
+			fields = append(fields, stringField("error", r.Error))
+		}
+
+		lines = append(lines, formatLine(metricName, tags, fields, r.Timestamp))
+	}
+	return lines
+}
+
+func (t *TCPPingTask) parseParams(params []interface{}) ([]models.TCPPingTarget, error) {
+	targets, err := decodeParams[models.TCPPingTarget](params)
+	if err != nil {
+		return nil, err
+	}
+
+	// 验证必要字段
+	for _, target := range targets {
+		if target.IP == "" {
+			return nil, fmt.Errorf("IP field is required")
+		}
+		if target.Port <= 0 || target.Port > 65535 {
+			return nil, fmt.Errorf("invalid port %d for target %s", target.Port, target.IP)
+		}
+	}
+	return targets, nil
+}
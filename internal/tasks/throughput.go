@@ -0,0 +1,116 @@
+package tasks
+
+import (
+	"context"
+	"fmt"
+	"net_detect/internal/models"
+	"net_detect/internal/storage"
+	"net_detect/internal/throughput"
+)
+
+// ThroughputTask agent间带宽测试任务
+type ThroughputTask struct {
+	tester  throughput.Tester
+	storage storage.ResultStorage
+}
+
+func NewThroughputTask(tester throughput.Tester, storage storage.ResultStorage) *ThroughputTask {
+	return &ThroughputTask{
+		tester:  tester,
+		storage: storage,
+	}
+}
+
+func (t *ThroughputTask) Name() string {
+	return "throughput"
+}
+
+func (t *ThroughputTask) Execute(ctx context.Context, metricName string, params []interface{}) (Summary, error) {
+	targets, err := t.parseParams(params)
+	if err != nil {
+		return Summary{}, err
+	}
+
+	// 逐个目标执行，避免多个测试互相争抢带宽
+	results := make([]models.ThroughputResult, 0, len(targets))
+	for _, target := range targets {
+		// ctx结束后不再开始新的测试
+		if ctx.Err() != nil {
+			break
+		}
+		results = append(results, t.tester.Run(ctx, target))
+	}
+
+	return summarize(results, func(r models.ThroughputResult) string { return r.Error }), t.storage.Store(t.resultInfulxDBFormat(metricName, results))
+}
+
+func (t *ThroughputTask) resultInfulxDBFormat(metricName string, results []models.ThroughputResult) []string {
+	var lines []string
+	for _, r := range results {
+		tags := sourceTags(r.SourceIP)
+		tags = append(tags, targetTags(r.TargetIP, r.TargetNode, r.TargetHost)...)
+		tags = append(tags, fmt.Sprintf("protocol=%s", r.Protocol))
+		tags = appendTags(tags, r.Tags)
+
+		fields := []string{
+			fmt.Sprintf("bytes_sent=%di", r.BytesSent),
+			fmt.Sprintf("bytes_recv=%di", r.BytesRecv),
+			fmt.Sprintf("duration=%f", r.Duration),
+			fmt.Sprintf("send_bps=%f", r.SendBps),
+			fmt.Sprintf("recv_bps=%f", r.RecvBps),
+		}
+		if r.Protocol == throughput.ProtocolUDP {
+			var lossRate float64
+			if r.PacketsSent > 0 {
+				lossRate = float64(r.PacketsSent-r.PacketsRecv) / float64(r.PacketsSent) * 100
+			}
+			fields = append(fields,
+				fmt.Sprintf("packets_sent=%di", r.PacketsSent),
+				fmt.Sprintf("packets_recv=%di", r.PacketsRecv),
+				fmt.Sprintf("packets_loss=%di", r.PacketsSent-r.PacketsRecv),
+				fmt.Sprintf("loss_rate=%f", lossRate),
+				fmt.Sprintf("duplicates=%di", r.Duplicates),
+			)
+		} else {
+			fields = append(fields, fmt.Sprintf("retransmits=%di", r.Retransmits))
+		}
+		if r.Error != "" {
+			fields = append(fields, stringField("error", r.Error))
+		}
+
+		lines = append(lines, formatLine(metricName, tags, fields, r.Timestamp))
+	}
+	return lines
+}
+
+func (t *ThroughputTask) parseParams(params []interface{}) ([]models.ThroughputTarget, error) {
+	targets, err := decodeParams[models.ThroughputTarget](params)
+	if err != nil {
+		return nil, err
+	}
+
+	// 验证必要字段
+	for i := range targets {
+		target := &targets[i]
+		if target.IP == "" {
+			return nil, fmt.Errorf("IP field is required")
+		}
+		switch target.Protocol {
+		case "":
+			target.Protocol = throughput.ProtocolTCP
+		case throughput.ProtocolTCP, throughput.ProtocolUDP:
+		default:
+			return nil, fmt.Errorf("unsupported protocol %q for %s", target.Protocol, target.IP)
+		}
+		if target.Duration > throughput.MaxDuration {
+			return nil, fmt.Errorf("duration must not exceed %s for %s", throughput.MaxDuration, target.IP)
+		}
+		if target.Bytes < 0 || target.Rate < 0 || target.PacketSize < 0 {
+			return nil, fmt.Errorf("bytes, rate and packetSize must not be negative for %s", target.IP)
+		}
+		if target.PacketSize > throughput.MaxPacketSize {
+			return nil, fmt.Errorf("packetSize must not exceed %d for %s", throughput.MaxPacketSize, target.IP)
+		}
+	}
+	return targets, nil
+}
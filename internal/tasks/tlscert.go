@@ -0,0 +1,224 @@
+package tasks
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"net_detect/internal/models"
+	"net_detect/internal/storage"
+	"net_detect/utils"
+)
+
+const defaultTLSTimeout = 5 * time.Second
+
+// TLSCertTask TLS证书与证书链检查任务
+type TLSCertTask struct {
+	storage storage.ResultStorage
+}
+
+func NewTLSCertTask(storage storage.ResultStorage) *TLSCertTask {
+	return &TLSCertTask{storage: storage}
+}
+
+func (t *TLSCertTask) Name() string {
+	return "tlsCert"
+}
+
+func (t *TLSCertTask) Execute(ctx context.Context, metricName string, params []interface{}) (Summary, error) {
+	targets, err := t.parseParams(params)
+	if err != nil {
+		return Summary{}, err
+	}
+
+	var wg sync.WaitGroup
+	results := make([]models.TLSCertResult, len(targets))
+
+	// 并发执行检查
+	for i, target := range targets {
+		wg.Add(1)
+		go func(index int, target models.TLSCertTarget) {
+			defer wg.Done()
+			results[index] = t.check(ctx, target)
+		}(i, target)
+	}
+	wg.Wait()
+
+	return summarize(results, func(r models.TLSCertResult) string { return r.Error }), t.storage.Store(t.resultInfulxDBFormat(metricName, results))
+}
+
+func (t *TLSCertTask) check(ctx context.Context, target models.TLSCertTarget) (result models.TLSCertResult) {
+	result = models.TLSCertResult{
+		TargetIP:   target.Host,
+		TargetPort: target.Port,
+		TargetNode: target.NodeName,
+		TargetHost: target.HostName,
+		SNI:        target.SNI,
+		Tags:       target.Tags,
+	}
+	defer func() { result.Timestamp = time.Now() }()
+
+	timeout := target.Timeout
+	if timeout <= 0 {
+		timeout = defaultTLSTimeout
+	}
+
+	// 跳过内置校验，取得证书后单独校验证书链和SAN，保证过期等情况下仍能记录证书信息
+	dialer := &tls.Dialer{
+		NetDialer: &net.Dialer{Timeout: timeout},
+		Config: &tls.Config{
+			ServerName:         target.SNI,
+			InsecureSkipVerify: true,
+			NextProtos:         []string{"h2", "http/1.1"},
+		},
+	}
+	// 先解析一次目标地址并以该地址建连，成功和失败的结果使用相同的target_ip、source_ip和ip_version
+	ip, err := resolveHost(ctx, target.Host, timeout)
+	if err != nil {
+		result.Error = fmt.Sprintf("解析地址失败: %v", err)
+		return result
+	}
+	result.TargetIP = ip
+	result.SourceIP, _ = utils.GetLocalIP(ip)
+
+	start := time.Now()
+	netConn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(ip, strconv.Itoa(target.Port)))
+	if err != nil {
+		result.Error = fmt.Sprintf("TLS握手失败: %v", err)
+		return result
+	}
+	conn := netConn.(*tls.Conn)
+	defer conn.Close()
+	result.HandshakeTime = msSince(start)
+
+	state := conn.ConnectionState()
+	result.Version = tls.VersionName(state.Version)
+	result.CipherSuite = tls.CipherSuiteName(state.CipherSuite)
+	result.ALPN = state.NegotiatedProtocol
+	if len(state.PeerCertificates) == 0 {
+		result.Error = "未收到服务端证书"
+		return result
+	}
+
+	leaf := state.PeerCertificates[0]
+	result.Subject = leaf.Subject.String()
+	result.Issuer = leaf.Issuer.String()
+	result.IssuerCN = leaf.Issuer.CommonName
+	result.Serial = leaf.SerialNumber.Text(16)
+	result.NotBefore = leaf.NotBefore
+	result.NotAfter = leaf.NotAfter
+	result.DaysToExpiry = time.Until(leaf.NotAfter).Hours() / 24
+	result.ChainLength = len(state.PeerCertificates)
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range state.PeerCertificates[1:] {
+		intermediates.AddCert(cert)
+	}
+	if _, err := leaf.Verify(x509.VerifyOptions{Intermediates: intermediates}); err != nil {
+		result.ChainError = err.Error()
+	} else {
+		result.ChainValid = true
+	}
+	// 未指定SNI时host为IP，校验IP SAN
+	name := target.SNI
+	if name == "" {
+		name = target.Host
+	}
+	result.SANMatch = leaf.VerifyHostname(name) == nil
+	return result
+}
+
+// resolveHost host为域名时解析并返回第一个地址
+func resolveHost(ctx context.Context, host string, timeout time.Duration) (string, error) {
+	if net.ParseIP(host) != nil {
+		return host, nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return "", err
+	}
+	if len(addrs) == 0 {
+		return "", fmt.Errorf("no address for %s", host)
+	}
+	return addrs[0].IP.String(), nil
+}
+
+func (t *TLSCertTask) resultInfulxDBFormat(metricName string, results []models.TLSCertResult) []string {
+	var lines []string
+	for _, r := range results {
+		tags := sourceTags(r.SourceIP)
+		tags = append(tags, targetTags(r.TargetIP, r.TargetNode, r.TargetHost)...)
+		tags = append(tags, fmt.Sprintf("target_port=%d", r.TargetPort))
+		if r.SNI != "" {
+			tags = append(tags, fmt.Sprintf("sni=%s", escapeTag(r.SNI)))
+		}
+		tags = appendTags(tags, r.Tags)
+
+		if r.Error != "" {
+			fields := []string{
+				"success=false",
+				stringField("error", r.Error),
+			}
+			lines = append(lines, formatLine(metricName, tags, fields, r.Timestamp))
+			continue
+		}
+
+		// 协商结果和证书信息作为field，保证成功与失败的结果tag集合一致
+		fields := []string{
+			"success=true",
+			fmt.Sprintf("handshake_time=%f", r.HandshakeTime),
+			fmt.Sprintf("days_to_expiry=%f", r.DaysToExpiry),
+			fmt.Sprintf("not_before=%di", r.NotBefore.Unix()),
+			fmt.Sprintf("not_after=%di", r.NotAfter.Unix()),
+			fmt.Sprintf("chain_length=%di", r.ChainLength),
+			fmt.Sprintf("chain_valid=%t", r.ChainValid),
+			fmt.Sprintf("san_match=%t", r.SANMatch),
+			stringField("tls_version", r.Version),
+			stringField("cipher", r.CipherSuite),
+			stringField("issuer_cn", r.IssuerCN),
+			stringField("alpn", r.ALPN),
+			stringField("subject", r.Subject),
+			stringField("issuer", r.Issuer),
+			stringField("serial", r.Serial),
+		}
+		if r.ChainError != "" {
+			fields = append(fields, stringField("chain_error", r.ChainError))
+		}
+
+		lines = append(lines, formatLine(metricName, tags, fields, r.Timestamp))
+	}
+	return lines
+}
+
+func (t *TLSCertTask) parseParams(params []interface{}) ([]models.TLSCertTarget, error) {
+	targets, err := decodeParams[models.TLSCertTarget](params)
+	if err != nil {
+		return nil, err
+	}
+
+	// 验证必要字段
+	for i := range targets {
+		target := &targets[i]
+		if target.Host == "" {
+			return nil, fmt.Errorf("host field is required")
+		}
+		if target.Port == 0 {
+			target.Port = 443
+		}
+		// host为域名时默认以其作为SNI
+		if target.SNI == "" && net.ParseIP(target.Host) == nil {
+			target.SNI = target.Host
+		}
+		if target.Port < 0 || target.Port > 65535 {
+			return nil, fmt.Errorf("invalid port %d for target %s", target.Port, target.Host)
+		}
+	}
+	return targets, nil
+}
@@ -0,0 +1,124 @@
+package tasks
+
+import (
+	"context"
+	"fmt"
+	"net_detect/internal/models"
+	"net_detect/internal/ping"
+	"net_detect/internal/storage"
+	"sync"
+)
+
+// TraceTask 逐跳路径探测任务（mtr），每跳单独输出一条序列
+type TraceTask struct {
+	tracer  ping.Tracer
+	storage storage.ResultStorage
+}
+
+func NewTraceTask(tracer ping.Tracer, storage storage.ResultStorage) *TraceTask {
+	return &TraceTask{
+		tracer:  tracer,
+		storage: storage,
+	}
+}
+
+func (t *TraceTask) Name() string {
+	return "mtr"
+}
+
+func (t *TraceTask) Execute(ctx context.Context, metricName string, params []interface{}) (Summary, error) {
+	targets, err := t.parseParams(params)
+	if err != nil {
+		return Summary{}, err
+	}
+
+	var wg sync.WaitGroup
+	results := make([]models.TraceResult, len(targets))
+
+	// 并发执行探测
+	for i, target := range targets {
+		wg.Add(1)
+		go func(index int, target models.TraceTarget) {
+			defer wg.Done()
+			results[index] = t.tracer.Trace(ctx, target)
+		}(i, target)
+	}
+	wg.Wait()
+
+	return summarize(results, func(r models.TraceResult) string { return r.Error }), t.storage.Store(t.resultInfulxDBFormat(metricName, results))
+}
+
+func (t *TraceTask) resultInfulxDBFormat(metricName string, results []models.TraceResult) []string {
+	var lines []string
+	for _, r := range results {
+		tags := sourceTags(r.SourceIP)
+		tags = append(tags, targetTags(r.TargetIP, r.TargetNode, r.TargetHost)...)
+		tags = append(tags, fmt.Sprintf("mode=%s", r.Mode))
+		tags = appendTags(tags, r.Tags)
+
+		if r.Error != "" {
+			fields := []string{
+				"reached=false",
+				stringField("error", r.Error),
+			}
+			lines = append(lines, formatLine(metricName, tags, fields, r.Timestamp))
+			continue
+		}
+
+		for _, hop := range r.Hops {
+			addr := hop.Addr
+			if addr == "" {
+				addr = "*"
+			}
+			hopTags := append(tags[:len(tags):len(tags)],
+				fmt.Sprintf("hop=%d", hop.TTL),
+				fmt.Sprintf("hop_ip=%s", addr),
+			)
+
+			var lossRate float64
+			if hop.Sent > 0 {
+				lossRate = float64(hop.Sent-hop.Recv) / float64(hop.Sent) * 100
+			}
+			fields := []string{
+				fmt.Sprintf("packets_sent=%di", hop.Sent),
+				fmt.Sprintf("packets_recv=%di", hop.Recv),
+				fmt.Sprintf("packets_loss=%di", hop.Sent-hop.Recv),
+				fmt.Sprintf("loss_rate=%f", lossRate),
+				fmt.Sprintf("rtt_min=%f", hop.MinRtt),
+				fmt.Sprintf("rtt_max=%f", hop.MaxRtt),
+				fmt.Sprintf("rtt_avg=%f", hop.AvgRtt),
+				fmt.Sprintf("rtt_std_dev=%f", hop.StdDevRtt),
+				fmt.Sprintf("destination=%t", hop.Destination),
+				fmt.Sprintf("reached=%t", r.Reached),
+			}
+			lines = append(lines, formatLine(metricName, hopTags, fields, r.Timestamp))
+		}
+	}
+	return lines
+}
+
+func (t *TraceTask) parseParams(params []interface{}) ([]models.TraceTarget, error) {
+	targets, err := decodeParams[models.TraceTarget](params)
+	if err != nil {
+		return nil, err
+	}
+
+	// 验证必要字段
+	for i := range targets {
+		target := &targets[i]
+		if target.IP == "" {
+			return nil, fmt.Errorf("IP field is required")
+		}
+		switch target.Mode {
+		case "":
+			target.Mode = ping.TraceModeICMP
+		case ping.TraceModeICMP, ping.TraceModeUDP, ping.TraceModeTCP:
+		default:
+			return nil, fmt.Errorf("unsupported trace mode %q for %s", target.Mode, target.IP)
+		}
+		if target.MaxHops > 255 {
+			return nil, fmt.Errorf("maxHops must not exceed 255 for %s", target.IP)
+		}
+	}
+	return targets, nil
+}
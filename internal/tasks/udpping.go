@@ -0,0 +1,105 @@
+package tasks
+
+import (
+	"context"
+	"fmt"
+	"net_detect/internal/models"
+	"net_detect/internal/ping"
+	"net_detect/internal/storage"
+	"sync"
+)
+
+// UDPPingTask UDP反射探测任务，分方向统计丢包、乱序和重复
+type UDPPingTask struct {
+	pinger  ping.UDPPinger
+	storage storage.ResultStorage
+}
+
+func NewUDPPingTask(pinger ping.UDPPinger, storage storage.ResultStorage) *UDPPingTask {
+	return &UDPPingTask{
+		pinger:  pinger,
+		storage: storage,
+	}
+}
+
+func (t *UDPPingTask) Name() string {
+	return "udpPing"
+}
+
+func (t *UDPPingTask) Execute(ctx context.Context, metricName string, params []interface{}) (Summary, error) {
+	targets, err := t.parseParams(params)
+	if err != nil {
+		return Summary{}, err
+	}
+
+	var wg sync.WaitGroup
+	results := make([]models.UDPPingResult, len(targets))
+
+	// 并发执行探测
+	for i, target := range targets {
+		wg.Add(1)
+		go func(index int, target models.UDPPingTarget) {
+			defer wg.Done()
+			results[index] = t.pinger.Ping(ctx, target)
+		}(i, target)
+	}
+	wg.Wait()
+
+	return summarize(results, func(r models.UDPPingResult) string { return r.Error }), t.storage.Store(t.resultInfulxDBFormat(metricName, results))
+}
+
+func (t *UDPPingTask) resultInfulxDBFormat(metricName string, results []models.UDPPingResult) []string {
+	var lines []string
+	for _, r := range results {
+		tags := sourceTags(r.SourceIP)
+		tags = append(tags, targetTags(r.TargetIP, r.TargetNode, r.TargetHost)...)
+		tags = appendTags(tags, r.Tags)
+
+		fields := []string{
+			fmt.Sprintf("packets_sent=%di", r.PacketsSent),
+			fmt.Sprintf("packets_recv=%di", r.PacketsRecv),
+			fmt.Sprintf("packets_loss=%di", r.PacketsSent-r.PacketsRecv),
+			fmt.Sprintf("rev_reordered=%di", r.RevReordered),
+			fmt.Sprintf("rev_duplicates=%di", r.RevDuplicates),
+			fmt.Sprintf("rtt_min=%f", r.MinRtt),
+			fmt.Sprintf("rtt_max=%f", r.MaxRtt),
+			fmt.Sprintf("rtt_avg=%f", r.AvgRtt),
+			fmt.Sprintf("rtt_std_dev=%f", r.StdDevRtt),
+			fmt.Sprintf("report_ok=%t", r.ReportOK),
+		}
+		// 只有取得反射端统计时才能区分方向
+		if r.ReportOK {
+			fields = append(fields,
+				fmt.Sprintf("fwd_recv=%di", r.FwdRecv),
+				fmt.Sprintf("fwd_loss=%di", r.FwdLoss),
+				fmt.Sprintf("fwd_reordered=%di", r.FwdReordered),
+				fmt.Sprintf("fwd_duplicates=%di", r.FwdDuplicates),
+				fmt.Sprintf("rev_loss=%di", r.RevLoss),
+			)
+		}
+		if r.Error != "" {
+			fields = append(fields, stringField("error", r.Error))
+		}
+
+		lines = append(lines, formatLine(metricName, tags, fields, r.Timestamp))
+	}
+	return lines
+}
+
+func (t *UDPPingTask) parseParams(params []interface{}) ([]models.UDPPingTarget, error) {
+	targets, err := decodeParams[models.UDPPingTarget](params)
+	if err != nil {
+		return nil, err
+	}
+
+	// 验证必要字段
+	for _, target := range targets {
+		if target.IP == "" {
+			return nil, fmt.Errorf("IP field is required")
+		}
+		if target.Port < 0 || target.Port > 65535 {
+			return nil, fmt.Errorf("invalid port %d for target %s", target.Port, target.IP)
+		}
+	}
+	return targets, nil
+}
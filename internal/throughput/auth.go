@@ -0,0 +1,123 @@
+package throughput
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxClockSkew 请求时间戳与接收端时间允许的最大偏差，超出时拒绝以防重放
+const maxClockSkew = time.Minute
+
+// Auth 接收端的访问控制，Secret和AllowedNets至少配置一项，两项都配置时需同时满足
+type Auth struct {
+	Secret      string       // 共享密钥，请求需携带以此计算的HMAC
+	AllowedNets []*net.IPNet // 允许发起测试的来源地址
+}
+
+func (a Auth) enabled() bool {
+	return a.Secret != "" || len(a.AllowedNets) > 0
+}
+
+// check 校验来源地址和请求签名
+func (a Auth) check(peer net.Addr, req request) error {
+	if len(a.AllowedNets) > 0 {
+		host, _, _ := net.SplitHostPort(peer.String())
+		ip := net.ParseIP(host)
+		allowed := false
+		for _, n := range a.AllowedNets {
+			if ip != nil && n.Contains(ip) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return fmt.Errorf("source %s not allowed", host)
+		}
+	}
+	if a.Secret == "" {
+		return nil
+	}
+	skew := time.Since(time.Unix(0, req.Timestamp))
+	if skew > maxClockSkew || skew < -maxClockSkew {
+		return errors.New("request timestamp out of range")
+	}
+	mac, err := hex.DecodeString(req.MAC)
+	if err != nil || !hmac.Equal(mac, signRequest(a.Secret, req)) {
+		return errors.New("invalid request signature")
+	}
+	return nil
+}
+
+// signRequest 以共享密钥对请求的会话ID、时间戳和测试参数计算HMAC-SHA256，密钥本身不在网络上传输
+func signRequest(secret string, req request) []byte {
+	h := hmac.New(sha256.New, []byte(secret))
+	var b [40]byte
+	binary.BigEndian.PutUint64(b[0:], req.Session)
+	binary.BigEndian.PutUint64(b[8:], uint64(req.Timestamp))
+	binary.BigEndian.PutUint64(b[16:], uint64(req.Duration))
+	binary.BigEndian.PutUint64(b[24:], uint64(req.Bytes))
+	binary.BigEndian.PutUint64(b[32:], uint64(req.PacketSize))
+	h.Write(b[:])
+	h.Write([]byte(req.Protocol))
+	return h.Sum(nil)
+}
+
+// replayCache 记录时间戳窗口内已使用过的请求签名，同一请求只能发起一次测试
+type replayCache struct {
+	mu   sync.Mutex
+	seen map[string]time.Time
+}
+
+// add 签名未出现过时记录并返回true，同时清理已超出时间戳窗口的记录
+func (c *replayCache) add(mac string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	if c.seen == nil {
+		c.seen = make(map[string]time.Time)
+	}
+	for k, at := range c.seen {
+		if now.Sub(at) > 2*maxClockSkew {
+			delete(c.seen, k)
+		}
+	}
+	if _, ok := c.seen[mac]; ok {
+		return false
+	}
+	c.seen[mac] = now
+	return true
+}
+
+// ParseNets 解析CIDR列表，单个IP视为/32或/128
+func ParseNets(cidrs []string) ([]*net.IPNet, error) {
+	nets := make([]*net.IPNet, 0, len(cidrs))
+	for _, c := range cidrs {
+		c = strings.TrimSpace(c)
+		if !strings.Contains(c, "/") {
+			ip := net.ParseIP(c)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid address: %s", c)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, n, err := net.ParseCIDR(c)
+		if err != nil {
+			return nil, err
+		}
+		nets = append(nets, n)
+	}
+	return nets, nil
+}
@@ -0,0 +1,237 @@
+package throughput
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net"
+	"strconv"
+	"time"
+
+	"net_detect/internal/models"
+	"net_detect/utils"
+)
+
+const (
+	defaultDuration   = 10 * time.Second
+	defaultUDPRate    = 100 * 1000 * 1000 // 100Mbit/s
+	defaultPacketSize = 1400
+)
+
+// Tester 带宽测试发送端接口
+type Tester interface {
+	Run(ctx context.Context, target models.ThroughputTarget) models.ThroughputResult
+}
+
+// DefaultTester 默认的带宽测试发送端
+type DefaultTester struct {
+	port   int
+	secret string // 接收端的共享密钥，为空时不签名
+}
+
+func NewTester(port int, secret string) *DefaultTester {
+	if port <= 0 {
+		port = DefaultPort
+	}
+	return &DefaultTester{port: port, secret: secret}
+}
+
+// Run 执行一次测试，ctx结束时停止发送并返回已发送部分的统计
+func (t *DefaultTester) Run(ctx context.Context, target models.ThroughputTarget) (result models.ThroughputResult) {
+	result = models.ThroughputResult{
+		TargetIP:   target.IP,
+		TargetNode: target.NodeName,
+		TargetHost: target.HostName,
+		Protocol:   target.Protocol,
+		Tags:       target.Tags,
+		IPVersion:  utils.GetIPVersion(target.IP),
+	}
+	defer func() { result.Timestamp = time.Now() }()
+
+	port := target.Port
+	if port == 0 {
+		port = t.port
+	}
+	duration := target.Duration
+	if duration <= 0 {
+		duration = defaultDuration
+	}
+	if duration > MaxDuration {
+		duration = MaxDuration
+	}
+
+	dialer := net.Dialer{Timeout: 5 * time.Second}
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(target.IP, strconv.Itoa(port)))
+	if err != nil {
+		result.Error = fmt.Sprintf("连接接收端失败: %v", err)
+		return result
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(duration + 30*time.Second))
+	// ctx结束时让控制连接上阻塞的读写立即返回
+	stop := context.AfterFunc(ctx, func() { conn.SetDeadline(time.Now()) })
+	defer stop()
+	result.SourceIP = conn.LocalAddr().(*net.TCPAddr).IP.String()
+
+	req := request{
+		Protocol:   target.Protocol,
+		Duration:   duration,
+		Bytes:      target.Bytes,
+		PacketSize: target.PacketSize,
+		Session:    rand.Uint64(),
+		Timestamp:  time.Now().UnixNano(),
+	}
+	if t.secret != "" {
+		req.MAC = hex.EncodeToString(signRequest(t.secret, req))
+	}
+	reader := bufio.NewReader(conn)
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		result.Error = fmt.Sprintf("发送测试请求失败: %v", err)
+		return result
+	}
+	var resp response
+	if err := readJSON(reader, &resp); err != nil {
+		result.Error = fmt.Sprintf("读取应答失败: %v", err)
+		return result
+	}
+	if !resp.OK {
+		result.Error = fmt.Sprintf("接收端拒绝测试: %s", resp.Error)
+		return result
+	}
+
+	var elapsed time.Duration
+	switch target.Protocol {
+	case ProtocolUDP:
+		elapsed, err = t.sendUDP(ctx, conn, target, req, resp.UDPPort, &result)
+	default:
+		elapsed, err = t.sendTCP(ctx, conn, req, &result)
+	}
+	result.Duration = float64(elapsed) / float64(time.Millisecond)
+	if elapsed > 0 {
+		result.SendBps = float64(result.BytesSent*8) / elapsed.Seconds()
+	}
+	// 被中断时接收端统计不完整，只返回发送端统计
+	if ctx.Err() != nil {
+		result.Error = fmt.Sprintf("测试中断: %v", ctx.Err())
+		return result
+	}
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	var rep report
+	if err := readJSON(reader, &rep); err != nil {
+		result.Error = fmt.Sprintf("读取测试结果失败: %v", err)
+		return result
+	}
+	if rep.Error != "" {
+		result.Error = fmt.Sprintf("接收端错误: %s", rep.Error)
+	}
+
+	result.BytesRecv = rep.Bytes
+	result.PacketsRecv = rep.Packets
+	result.Duplicates = rep.Duplicates
+	if rep.Duration > 0 {
+		result.RecvBps = float64(rep.Bytes*8) / rep.Duration.Seconds()
+	}
+	return result
+}
+
+// sendTCP 在控制连接上发送数据，达到时长或字节上限后半关闭连接
+func (t *DefaultTester) sendTCP(ctx context.Context, conn net.Conn, req request, result *models.ThroughputResult) (time.Duration, error) {
+	buf := make([]byte, 128*1024)
+	rand.Read(buf)
+
+	start := time.Now()
+	deadline := start.Add(req.Duration)
+	conn.SetWriteDeadline(deadline)
+	for time.Now().Before(deadline) && ctx.Err() == nil {
+		chunk := buf
+		if req.Bytes > 0 {
+			remain := req.Bytes - result.BytesSent
+			if remain <= 0 {
+				break
+			}
+			if remain < int64(len(chunk)) {
+				chunk = chunk[:remain]
+			}
+		}
+		n, err := conn.Write(chunk)
+		result.BytesSent += int64(n)
+		if err != nil {
+			if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+				break
+			}
+			return time.Since(start), fmt.Errorf("发送数据失败: %v", err)
+		}
+	}
+	elapsed := time.Since(start)
+	result.Retransmits = tcpRetransmits(conn)
+
+	conn.SetWriteDeadline(time.Time{})
+	if err := conn.(*net.TCPConn).CloseWrite(); err != nil {
+		return elapsed, fmt.Errorf("关闭发送方向失败: %v", err)
+	}
+	return elapsed, nil
+}
+
+// sendUDP 按指定速率向接收端的临时端口发送带序号的报文
+func (t *DefaultTester) sendUDP(ctx context.Context, conn net.Conn, target models.ThroughputTarget, req request, port int, result *models.ThroughputResult) (time.Duration, error) {
+	udpConn, err := net.Dial("udp", net.JoinHostPort(target.IP, strconv.Itoa(port)))
+	if err != nil {
+		return 0, fmt.Errorf("创建UDP连接失败: %v", err)
+	}
+	defer udpConn.Close()
+
+	size := target.PacketSize
+	if size <= udpHeaderSize {
+		size = defaultPacketSize
+	}
+	rate := target.Rate
+	if rate <= 0 {
+		rate = defaultUDPRate
+	}
+	// 按速率计算每个报文的发送间隔
+	gap := time.Duration(float64(size*8) / float64(rate) * float64(time.Second))
+
+	buf := make([]byte, size)
+	binary.BigEndian.PutUint64(buf, req.Session)
+	start := time.Now()
+	deadline := start.Add(req.Duration)
+	next := start
+	for now := start; now.Before(deadline) && ctx.Err() == nil; now = time.Now() {
+		if req.Bytes > 0 && result.BytesSent+int64(size) > req.Bytes {
+			break
+		}
+		if wait := next.Sub(now); wait > 0 {
+			time.Sleep(wait)
+		}
+		next = next.Add(gap)
+
+		binary.BigEndian.PutUint64(buf[8:], uint64(result.PacketsSent))
+		if _, err := udpConn.Write(buf); err != nil {
+			return time.Since(start), fmt.Errorf("发送报文失败: %v", err)
+		}
+		result.PacketsSent++
+		result.BytesSent += int64(size)
+	}
+	elapsed := time.Since(start)
+
+	if err := json.NewEncoder(conn).Encode(done{PacketsSent: result.PacketsSent}); err != nil {
+		return elapsed, fmt.Errorf("发送结束通知失败: %v", err)
+	}
+	return elapsed, nil
+}
+
+func readJSON(reader *bufio.Reader, v interface{}) error {
+	line, err := reader.ReadBytes('\n')
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(line, v)
+}
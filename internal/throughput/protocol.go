@@ -0,0 +1,51 @@
+package throughput
+
+import "time"
+
+const (
+	ProtocolTCP = "tcp"
+	ProtocolUDP = "udp"
+
+	// DefaultPort 目标未指定端口时发送端使用的接收端端口
+	DefaultPort = 8863
+	// MaxDuration 单次测试的最长时间，防止发送端长时间占用带宽
+	MaxDuration = 60 * time.Second
+	// MaxPacketSize UDP测试报文的最大长度，即IPv4下UDP负载的上限
+	MaxPacketSize = 65507
+	// udpHeaderSize UDP测试报文头部：会话ID(8) + 序号(8)
+	udpHeaderSize = 16
+	// udpGrace 发送结束后等待在途UDP报文的时间
+	udpGrace = 500 * time.Millisecond
+)
+
+// request 发送端在控制连接上发出的测试请求
+type request struct {
+	Protocol   string        `json:"protocol"`
+	Duration   time.Duration `json:"duration"`
+	Bytes      int64         `json:"bytes"`
+	PacketSize int           `json:"packetSize,omitempty"`
+	Session    uint64        `json:"session"`
+	Timestamp  int64         `json:"timestamp"`     // 发送端UnixNano时间
+	MAC        string        `json:"mac,omitempty"` // 配置共享密钥时的请求签名，hex编码
+}
+
+// response 接收端对测试请求的应答
+type response struct {
+	OK      bool   `json:"ok"`
+	Error   string `json:"error,omitempty"`
+	UDPPort int    `json:"udpPort,omitempty"`
+}
+
+// done UDP测试结束时发送端告知发送数量
+type done struct {
+	PacketsSent int64 `json:"packetsSent"`
+}
+
+// report 接收端统计的测试结果
+type report struct {
+	Bytes      int64         `json:"bytes"`
+	Packets    int64         `json:"packets"`
+	Duplicates int64         `json:"duplicates"`
+	Duration   time.Duration `json:"duration"` // 首个到最后一个数据到达的时间
+	Error      string        `json:"error,omitempty"`
+}
@@ -0,0 +1,220 @@
+package throughput
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Server 带宽测试接收端，按需为每次测试打开TCP/UDP数据通道，同一时间只允许一个测试。
+// 必须配置共享密钥或来源白名单，避免被任意来源用于消耗带宽
+type Server struct {
+	addr     string
+	auth     Auth
+	replays  replayCache
+	listener net.Listener
+	busy     chan struct{}
+	mu       sync.Mutex
+	stopCh   chan struct{}
+}
+
+func NewServer(addr string, auth Auth) *Server {
+	return &Server{
+		addr:   addr,
+		auth:   auth,
+		busy:   make(chan struct{}, 1),
+		stopCh: make(chan struct{}),
+	}
+}
+
+// Start 监听控制端口并处理测试请求，直到Stop被调用
+func (s *Server) Start() error {
+	if !s.auth.enabled() {
+		return errors.New("throughput server requires a shared secret or an allowlist")
+	}
+	listener, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.listener = listener
+	s.mu.Unlock()
+	log.Printf("Throughput server listening on %s", listener.Addr())
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			select {
+			case <-s.stopCh:
+				return nil
+			default:
+			}
+			log.Printf("Throughput server accept error: %v", err)
+			time.Sleep(100 * time.Millisecond)
+			continue
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *Server) Stop() {
+	close(s.stopCh)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.listener != nil {
+		s.listener.Close()
+	}
+}
+
+func (s *Server) handle(conn net.Conn) {
+	defer conn.Close()
+
+	reader := bufio.NewReader(conn)
+	encoder := json.NewEncoder(conn)
+	conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+
+	var req request
+	line, err := reader.ReadBytes('\n')
+	if err != nil {
+		return
+	}
+	if err := json.Unmarshal(line, &req); err != nil {
+		encoder.Encode(response{Error: fmt.Sprintf("invalid request: %v", err)})
+		return
+	}
+	if err := s.auth.check(conn.RemoteAddr(), req); err != nil {
+		log.Printf("Throughput server rejected %s: %v", conn.RemoteAddr(), err)
+		encoder.Encode(response{Error: "unauthorized"})
+		return
+	}
+	// 签名只防篡改，时间戳窗口内截获的请求仍可重放，需记录已使用的签名
+	if s.auth.Secret != "" && !s.replays.add(strings.ToLower(req.MAC)) {
+		log.Printf("Throughput server rejected replayed request from %s", conn.RemoteAddr())
+		encoder.Encode(response{Error: "unauthorized"})
+		return
+	}
+	if req.Duration <= 0 || req.Duration > MaxDuration {
+		encoder.Encode(response{Error: fmt.Sprintf("duration must be in (0, %s]", MaxDuration)})
+		return
+	}
+
+	select {
+	case s.busy <- struct{}{}:
+		defer func() { <-s.busy }()
+	default:
+		encoder.Encode(response{Error: "busy"})
+		return
+	}
+
+	// 读超时放宽到测试时长之外，避免发送端排队导致误判
+	conn.SetReadDeadline(time.Now().Add(req.Duration + 10*time.Second))
+	switch req.Protocol {
+	case ProtocolTCP:
+		encoder.Encode(response{OK: true})
+		encoder.Encode(s.receiveTCP(reader))
+	case ProtocolUDP:
+		s.receiveUDP(conn, reader, encoder, req)
+	default:
+		encoder.Encode(response{Error: fmt.Sprintf("unsupported protocol: %s", req.Protocol)})
+	}
+}
+
+// receiveTCP 在控制连接上接收数据直到发送端半关闭
+func (s *Server) receiveTCP(reader io.Reader) report {
+	var rep report
+	buf := make([]byte, 128*1024)
+	var first, last time.Time
+	for {
+		n, err := reader.Read(buf)
+		if n > 0 {
+			last = time.Now()
+			if first.IsZero() {
+				first = last
+			}
+			rep.Bytes += int64(n)
+		}
+		if err != nil {
+			if err != io.EOF {
+				rep.Error = err.Error()
+			}
+			break
+		}
+	}
+	rep.Duration = last.Sub(first)
+	return rep
+}
+
+// receiveUDP 打开临时UDP端口接收数据，发送端在控制连接上通知结束后返回统计
+func (s *Server) receiveUDP(conn net.Conn, reader *bufio.Reader, encoder *json.Encoder, req request) {
+	host, _, _ := net.SplitHostPort(conn.LocalAddr().String())
+	udpConn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.ParseIP(host)})
+	if err != nil {
+		encoder.Encode(response{Error: fmt.Sprintf("listen udp failed: %v", err)})
+		return
+	}
+	defer udpConn.Close()
+	encoder.Encode(response{OK: true, UDPPort: udpConn.LocalAddr().(*net.UDPAddr).Port})
+
+	var (
+		mu       sync.Mutex
+		rep      report
+		first    time.Time
+		last     time.Time
+		seen     = make(map[uint64]struct{})
+		finished = make(chan struct{})
+	)
+	go func() {
+		defer close(finished)
+		buf := make([]byte, 65535)
+		for {
+			n, err := udpConn.Read(buf)
+			if err != nil {
+				return
+			}
+			if n < udpHeaderSize || binary.BigEndian.Uint64(buf) != req.Session {
+				continue
+			}
+			now := time.Now()
+			seq := binary.BigEndian.Uint64(buf[8:])
+			mu.Lock()
+			if _, dup := seen[seq]; dup {
+				rep.Duplicates++
+			} else {
+				seen[seq] = struct{}{}
+				rep.Packets++
+				rep.Bytes += int64(n)
+			}
+			if first.IsZero() {
+				first = now
+			}
+			last = now
+			mu.Unlock()
+		}
+	}()
+
+	// 等待发送端结束通知，再给在途报文留出时间
+	var d done
+	line, err := reader.ReadBytes('\n')
+	if err == nil {
+		err = json.Unmarshal(line, &d)
+	}
+	time.Sleep(udpGrace)
+	udpConn.Close()
+	<-finished
+
+	mu.Lock()
+	defer mu.Unlock()
+	rep.Duration = last.Sub(first)
+	if err != nil {
+		rep.Error = err.Error()
+	}
+	encoder.Encode(rep)
+}
@@ -0,0 +1,28 @@
+//go:build linux
+
+package throughput
+
+import (
+	"net"
+
+	"golang.org/x/sys/unix"
+)
+
+// tcpRetransmits 读取连接的TCP累计重传次数
+func tcpRetransmits(conn net.Conn) int64 {
+	tcpConn, ok := conn.(*net.TCPConn)
+	if !ok {
+		return 0
+	}
+	raw, err := tcpConn.SyscallConn()
+	if err != nil {
+		return 0
+	}
+	var retrans int64
+	raw.Control(func(fd uintptr) {
+		if info, err := unix.GetsockoptTCPInfo(int(fd), unix.IPPROTO_TCP, unix.TCP_INFO); err == nil {
+			retrans = int64(info.Total_retrans)
+		}
+	})
+	return retrans
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+
+package throughput
+
+import "net"
+
+// tcpRetransmits 非linux平台无法读取TCP_INFO
+func tcpRetransmits(conn net.Conn) int64 {
+	return 0
+}
@@ -82,3 +82,20 @@ func GetNodeName() (string, error) {
 	}
 
 }
+
+// GetLocalIPs 获取本机所有非回环、非链路本地的单播地址
+func GetLocalIPs() ([]string, error) {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return nil, err
+	}
+	var ips []string
+	for _, addr := range addrs {
+		ipnet, ok := addr.(*net.IPNet)
+		if !ok || ipnet.IP.IsLoopback() || ipnet.IP.IsLinkLocalUnicast() {
+			continue
+		}
+		ips = append(ips, ipnet.IP.String())
+	}
+	return ips, nil
+}
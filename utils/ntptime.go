@@ -0,0 +1,21 @@
+package utils
+
+import "time"
+
+// ntpEpochOffset NTP纪元(1900-01-01)与Unix纪元之间的秒数
+const ntpEpochOffset = 2208988800
+
+// ToNTPTime 将时间转换为64位NTP时间戳，高32位为秒，低32位为秒的小数部分
+func ToNTPTime(t time.Time) uint64 {
+	nsec := uint64(t.UnixNano()) + ntpEpochOffset*uint64(time.Second)
+	sec := nsec / uint64(time.Second)
+	frac := (nsec % uint64(time.Second)) << 32 / uint64(time.Second)
+	return sec<<32 | frac
+}
+
+// FromNTPTime 将64位NTP时间戳转换为时间
+func FromNTPTime(ts uint64) time.Time {
+	sec := int64(ts>>32) - ntpEpochOffset
+	nsec := int64((ts & 0xffffffff) * uint64(time.Second) >> 32)
+	return time.Unix(sec, nsec)
+}